@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// flagSpec describes a command line flag. Flags may be given anywhere after
+// "tgo", before or after the command, as --name value, --name=value or -s value.
+// Other words starting with a dash are arguments, e.g. "-5m" or "- bullet",
+// and "--" makes every following word an argument.
+type flagSpec struct {
+	name     string
+	short    string
+	hasValue bool
+}
+
+var cliFlags = []flagSpec{
+	{name: "list", short: "l", hasValue: true},
+	{name: "help", short: "h"},
+	{name: "json"},
+	{name: "format", hasValue: true},
+}
+
+// cliArgs is a parsed command line.
+type cliArgs struct {
+	command string
+	args    []string
+	flags   map[string]string
+}
+
+func parseArgs(argv []string) (*cliArgs, error) {
+	parsed := &cliArgs{flags: make(map[string]string)}
+	var positional []string
+
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		if arg == "--" {
+			positional = append(positional, argv[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		spec := findFlag(name, !strings.HasPrefix(arg, "--"))
+		if spec == nil {
+			positional = append(positional, arg)
+			continue
+		}
+
+		switch {
+		case spec.hasValue && !hasValue:
+			if i+1 >= len(argv) {
+				return nil, fmt.Errorf("flag %s needs a value", arg)
+			}
+			value = argv[i+1]
+			i++
+		case !spec.hasValue && hasValue:
+			return nil, fmt.Errorf("flag --%s doesn't take a value", spec.name)
+		case !spec.hasValue:
+			value = "true"
+		}
+		parsed.flags[spec.name] = value
+	}
+
+	if len(positional) > 0 {
+		parsed.command = positional[0]
+		parsed.args = positional[1:]
+	}
+	return parsed, nil
+}
+
+func findFlag(name string, short bool) *flagSpec {
+	for i := range cliFlags {
+		if (short && cliFlags[i].short == name) || (!short && cliFlags[i].name == name) {
+			return &cliFlags[i]
+		}
+	}
+	return nil
+}
+
+func (a *cliArgs) flag(name string) string {
+	return a.flags[name]
+}
+
+func (a *cliArgs) has(name string) bool {
+	_, ok := a.flags[name]
+	return ok
+}
+
+// requestedList is the list named with --list, or else in $TGO_LIST.
+func (a *cliArgs) requestedList() string {
+	if list := a.flag("list"); list != "" {
+		return list
+	}
+	return os.Getenv("TGO_LIST")
+}
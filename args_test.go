@@ -0,0 +1,78 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		argv    []string
+		command string
+		args    []string
+		flags   map[string]string
+		err     bool
+	}{
+		{
+			argv:    []string{"log", "3", "-5m"},
+			command: "log",
+			args:    []string{"3", "-5m"},
+		},
+		{
+			argv:    []string{"add", "-", "bullet"},
+			command: "add",
+			args:    []string{"-", "bullet"},
+		},
+		{
+			argv:    []string{"add", "--", "--list", "-l", "x"},
+			command: "add",
+			args:    []string{"--list", "-l", "x"},
+		},
+		{
+			argv:    []string{"--list=x", "add", "task"},
+			command: "add",
+			args:    []string{"task"},
+			flags:   map[string]string{"list": "x"},
+		},
+		{
+			argv:    []string{"add", "task", "-l", "x", "--json"},
+			command: "add",
+			args:    []string{"task"},
+			flags:   map[string]string{"list": "x", "json": "true"},
+		},
+		{
+			argv:    []string{"list", "--format", "csv"},
+			command: "list",
+			flags:   map[string]string{"format": "csv"},
+		},
+		{
+			argv:    []string{"add", "--unknown"},
+			command: "add",
+			args:    []string{"--unknown"},
+		},
+		{argv: []string{"add", "task", "--list"}, err: true},
+		{argv: []string{"add", "-l"}, err: true},
+		{argv: []string{"list", "--json=yes"}, err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseArgs(tt.argv)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseArgs(%q) succeeded, want an error", tt.argv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseArgs(%q): %v", tt.argv, err)
+			continue
+		}
+		if got.command != tt.command || !slices.Equal(got.args, tt.args) {
+			t.Errorf("parseArgs(%q) = %q %q, want %q %q", tt.argv, got.command, got.args, tt.command, tt.args)
+		}
+		if len(got.flags) != len(tt.flags) || !maps.Equal(got.flags, tt.flags) {
+			t.Errorf("parseArgs(%q) flags = %v, want %v", tt.argv, got.flags, tt.flags)
+		}
+	}
+}
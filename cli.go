@@ -17,26 +17,115 @@ func printUsage() {
 
 Usage:
   tgo                      - Interactive task management
-  tgo start <number>       - Start/stop task timer
-  tgo done <number>        - Mark task complete
+  tgo list [filter]...     - Print tasks (--json or --format json|text),
+                             filter by +tag or status, e.g. "+bug waiting"
+  tgo add <task>           - Add new task, +tag words become tags
+  tgo start <task>         - Start/stop task timer (or subtask, e.g. 3.2)
+  tgo status               - Show running timers of all lists (--json)
+  tgo done <task>          - Mark task complete, or reopen a completed one
+  tgo reopen <task>        - Reopen a done, cancelled or waiting task
+  tgo history <task>       - Show the status changes of a task
+  tgo log <task> <time>    - Log time worked: 45m, 09:00-10:30 or
+                             yesterday 09:00-10:30
+  tgo sessions <task>      - List the sessions of a task
+  tgo sessions <task> <n> set [day] <from>-<to> | set <length> |
+                          split <time> | rm
+                           - Change, split or delete a session
+  tgo pomo <task>          - Run pomodoros on a task, Enter stops
+  tgo recover [task] [keep|last|at [day] <time>|discard]
+                           - List timers left running, or keep, stop (at
+                             the last activity or a time) or discard one
+  tgo cancel <task>        - Cancel task, or restore a cancelled one
+  tgo wait <task> [note] [until <when>]
+                           - Put task on hold, e.g. "wait 3 Anna until fri"
+                             ('-' takes it off hold)
+  tgo edit <task> <title>  - Rename task
+  tgo note <task> [text]   - Set task note ($EDITOR without text, '-' clears)
+  tgo due                  - Show upcoming tasks of all lists
+  tgo due <task> <when>    - Set due date (tomorrow, fri 17:00, 2026-11-03,
+                             +3d, '-' clears)
+  tgo sub <task> <title>   - Add a subtask
+  tgo check <task>.<n>     - Check off or reopen a subtask
+  tgo unsub <task>.<n>     - Remove a subtask
+  tgo repeat <task> <rule> - Repeat task: daily, weekdays, weekly [mon,thu],
+                             monthly or every <n>d after completion
+  tgo dep <task> <other>   - Block task until the other task is done
+  tgo undep <task> <other> - Remove that dependency
+  tgo move <task> <pos>    - Move task to another position in the list
+  tgo mv <task> <list>     - Move task to another list, with its history
+  tgo prio <task> <H|M|L>  - Set task priority ('-' clears)
+  tgo sort [mode]          - Sort the list by manual, priority, due, created
+                             or time
+  tgo tag <task> <tag>...  - Add tags to a task
+  tgo untag <task> <tag>.. - Remove tags from a task
   tgo set-dir <path>    - Configure task directory
   tgo create-list <name>   - Create new task list
-  tgo remove-list          - Remove task list
+  tgo remove-list [name]   - Remove task list
+  tgo merge-conflicts      - Merge sync conflict copies into their lists
+  tgo config [key] [value] - Show or change settings
+  tgo import <json|sqlite> - Copy the lists of the other store into this one
   tgo help                 - Show this help
 
+Options:
+  -l, --list <name>        - List to work on, instead of $TGO_LIST, the
+                             default list or the last used list
+  -h, --help               - Show this help
+
+Tasks are given by their number or by the short ID shown next to it
+(e.g. k3f9). IDs never change, numbers shift when tasks are removed.
+
 Interactive Commands:
-  <number>        - Start/stop task timer
-  add <task>      - Add new task
-  remove <number> - Remove task
-  done <number>   - Mark task complete
+  <task>          - Start/stop task timer
+  add <title>     - Add new task
+  remove <task>   - Remove task
+  done <task>     - Mark task complete, or reopen a completed one
+  reopen <task>   - Reopen a done, cancelled or waiting task
+  history <task>  - Show the status changes of a task
+  pomo <task>     - Run pomodoros on a task, Enter stops
+  log <task> <time>    - Log time worked: 45m or [day] 09:00-10:30
+  sessions <task> [<n> set|split|rm ...] - List or change sessions
+  cancel <task>        - Cancel task, or restore a cancelled one
+  wait <task> [note] [until <when>] - Put task on hold ('-' takes it off)
+  edit <task> <title>  - Rename task
+  note <task> [text]   - Set task note ($EDITOR without text, '-' clears)
+  due <task> <when>    - Set due date ('-' clears)
+  sub <task> <title>   - Add a subtask, <task>.<n> starts/stops its timer
+  check <task>.<n>     - Check off or reopen a subtask
+  unsub <task>.<n>     - Remove a subtask
+  repeat <task> <rule> - Repeat task when done ('-' stops)
+  dep / undep <task> <other> - Add or remove a dependency
+  move <task> <pos>    - Move task to another position
+  mv <task> <list>     - Move task to another list
+  prio <task> <H|M|L>  - Set task priority ('-' clears)
+  sort <mode>          - Sort by manual, priority, due, created or time
+  tag / untag <task> <tag>... - Add or remove tags
+  filter [+tag|status]... - Only show matching tasks, no arguments shows all
   r | return      - Return to main menu
   q | quit        - Exit program
 
+Settings:
+  store           - json (default, one file per list) or sqlite
+  default-list    - List used when no --list is given ('-' to clear)
+  stale-after     - Ask about timers running longer than this (default 8h,
+                    'off' only asks about timers running past midnight)
+  single-timer    - on: starting a task pauses timers in other lists
+  pomo-focus, pomo-break, pomo-long-break
+                  - Pomodoro lengths (default 25m, 5m and 15m, every 4th
+                    break is a long one)
+  idle-after      - In interactive mode, ask what to do with the time away
+                    after this long without input (default 15m, or 'off')
+
 Examples:
   tgo set-dir ~/Tasks
+  tgo config store sqlite
+  tgo add --list work "Review PR +acme +review"
   tgo create-list "Sprint Planning"
   tgo
   tgo start 3
+  tgo done -l work 2
+  tgo list --json
+  tgo list +bug
+  tgo due 3 fri 17:00
 `)
 }
 
@@ -47,405 +136,882 @@ func runCLI() {
 		os.Exit(1)
 	}
 
-	if len(os.Args) < 2 {
-		runInteractiveMode(config)
+	cli, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		os.Exit(1)
+	}
+
+	if cli.has("help") {
+		printUsage()
 		return
 	}
 
-	command := os.Args[1]
-	switch command {
+	if cli.command == "" {
+		runInteractiveMode(config, cli.requestedList())
+		return
+	}
+
+	switch cli.command {
 	case "set-dir":
-		handleSetFolder(config)
+		err = handleSetFolder(config, cli)
 	case "create-list":
-		handleCreateList(config)
+		err = handleCreateList(config, cli)
 	case "remove-list":
-		handleRemoveList(config)
+		err = handleRemoveList(config, cli)
+	case "list", "ls":
+		err = handleList(config, cli)
+	case "add":
+		err = handleAdd(config, cli)
 	case "start":
-		handleStartTask(config)
+		err = handleStartTask(config, cli)
 	case "done":
-		handleMarkDone(config)
-	case "help", "-h", "--help":
+		err = handleMarkDone(config, cli)
+	case "reopen":
+		err = updateTaskFromArgs(config, cli, reopenTask)
+	case "history":
+		err = handleHistory(config, cli)
+	case "log":
+		err = handleLog(config, cli)
+	case "sessions":
+		err = handleSessions(config, cli)
+	case "status":
+		err = handleStatus(config, cli)
+	case "pomo":
+		err = handlePomodoro(config, cli)
+	case "recover":
+		err = handleRecover(config, cli)
+	case "cancel":
+		err = updateTaskFromArgs(config, cli, stopsTimers(config, cancelTask))
+	case "wait":
+		err = handleWait(config, cli)
+	case "edit":
+		err = handleEdit(config, cli)
+	case "tag":
+		err = handleTag(config, cli, tagTask)
+	case "untag":
+		err = handleTag(config, cli, untagTask)
+	case "note":
+		err = handleNote(config, cli)
+	case "due":
+		err = handleDue(config, cli)
+	case "sub":
+		err = handleSub(config, cli)
+	case "check":
+		err = updateSubtaskFromArgs(config, cli, func(taskList *TaskList, index, child int) error {
+			if err := checkStaleTimers(config, taskList); err != nil {
+				return err
+			}
+			return toggleSubtaskDone(taskList, index, child)
+		})
+	case "unsub":
+		err = updateSubtaskFromArgs(config, cli, removeSubtask)
+	case "repeat":
+		err = handleRepeat(config, cli)
+	case "dep":
+		err = handleDependency(config, cli, addDependency)
+	case "undep":
+		err = handleDependency(config, cli, removeDependency)
+	case "move":
+		err = handleMove(config, cli)
+	case "mv":
+		err = handleMoveToList(config, cli)
+	case "prio":
+		err = handlePriority(config, cli)
+	case "sort":
+		err = handleSort(config, cli)
+	case "merge-conflicts":
+		err = handleMergeConflicts(config)
+	case "import":
+		err = handleImport(config, cli)
+	case "config":
+		err = handleConfig(config, cli)
+	case "help":
 		printUsage()
 	default:
-		fmt.Printf("[!] Unknown command: %s\n", command)
+		fmt.Printf("[!] Unknown command: %s\n", cli.command)
 		printUsage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		os.Exit(1)
 	}
 }
 
-func runInteractiveMode(config *Config) {
-	if config.TaskDir == "" {
-		fmt.Println("[!] No task directory configured")
-		fmt.Println("Use: tgo set-dir <path>")
-		return
+func handleSetFolder(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("folder path required")
 	}
 
-	taskFiles, err := findTaskFiles(config.TaskDir)
-	if err != nil {
-		fmt.Printf("[i] No task lists found in: %s\n\n", config.TaskDir)
-		fmt.Println("Let's create your first task list!")
-		handleCreateFirstList(config)
-		return
+	folder := cli.args[0]
+	if strings.HasPrefix(folder, "~/") {
+		home, _ := os.UserHomeDir()
+		folder = filepath.Join(home, folder[2:])
 	}
 
-	taskFile, err := selectTaskFile(config.TaskDir, taskFiles)
+	absDir, err := filepath.Abs(folder)
 	if err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+		return fmt.Errorf("invalid path: %v", err)
 	}
 
-	taskList, err := loadTasks(taskFile)
+	if _, err := os.Stat(absDir); os.IsNotExist(err) {
+		return fmt.Errorf("directory not found: %s", absDir)
+	}
+
+	config.TaskDir = absDir
+	config.LastList = ""
+	if err := saveConfig(config); err != nil {
+		return fmt.Errorf("save error: %v", err)
+	}
+
+	fmt.Printf("[+] Task directory set: %s\n", absDir)
+	showDirContents(absDir)
+	return nil
+}
+
+func handleCreateList(config *Config, cli *cliArgs) error {
+	store, err := openStore(config)
 	if err != nil {
-		fmt.Printf("[!] Error loading tasks: %v\n", err)
-		return
+		return err
 	}
 
-	runInteractiveLoop(taskList, taskFile)
+	var listName string
+	if len(cli.args) == 0 {
+		fmt.Print("Enter list name: ")
+		scanner := bufio.NewScanner(os.Stdin)
+		if scanner.Scan() {
+			listName = strings.TrimSpace(scanner.Text())
+		}
+		if listName == "" {
+			return fmt.Errorf("list name cannot be empty")
+		}
+	} else {
+		listName = strings.Join(cli.args, " ")
+	}
+
+	if _, err := createList(store, listName); err != nil {
+		return err
+	}
+
+	fmt.Printf("[+] Created list: %s\n", listName)
+	showLists(config, store)
+	return nil
 }
 
-func runInteractiveLoop(taskList *TaskList, taskFile string) {
-	reader := bufio.NewReader(os.Stdin)
-	spinnerStart := time.Now()
+func handleRemoveList(config *Config, cli *cliArgs) error {
+	store, err := openStore(config)
+	if err != nil {
+		return err
+	}
 
-	render := func() {
-		frame := int(time.Since(spinnerStart) / (150 * time.Millisecond))
-		displayTaskListWithSpinner(taskList, filepath.Base(taskFile), frame)
-		fmt.Print("\n> ")
+	listNames, err := store.Lists()
+	if err != nil {
+		return err
+	}
+	if len(listNames) == 0 {
+		showLists(config, store)
+		return fmt.Errorf("no task lists found")
 	}
 
-	for {
-		render()
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			return
-		}
+	var selected string
+	requested := cli.requestedList()
+	if len(cli.args) > 0 {
+		requested = strings.Join(cli.args, " ")
+	}
 
-		input := strings.TrimSpace(line)
-		if input == "" {
-			continue
+	switch {
+	case requested != "":
+		if selected, err = findList(listNames, requested); err != nil {
+			return err
+		}
+	case len(listNames) == 1:
+		selected = listNames[0]
+	default:
+		fmt.Printf("[i] Found %d task lists:\n\n", len(listNames))
+		for i, name := range listNames {
+			fmt.Printf("%d. %s\n", i+1, name)
 		}
 
-		if handleInteractiveCommand(input, taskList, taskFile) {
-			return
+		fmt.Printf("\nSelect list to remove (1-%d): ", len(listNames))
+		var choice int
+		if _, err := fmt.Scanf("%d", &choice); err != nil || choice < 1 || choice > len(listNames) {
+			return fmt.Errorf("invalid selection")
 		}
+		selected = listNames[choice-1]
+	}
 
-		spinnerStart = time.Now()
+	fmt.Printf("Remove '%s'? (y/N): ", selected)
+	scanner := bufio.NewScanner(os.Stdin)
+	if scanner.Scan() && strings.ToLower(scanner.Text()) == "y" {
+		if err := store.Delete(selected); err != nil {
+			return fmt.Errorf("failed to remove: %v", err)
+		}
+		fmt.Printf("[-] Removed: %s\n", selected)
 	}
+	return nil
 }
 
-func handleInteractiveCommand(input string, taskList *TaskList, taskFile string) bool {
-	switch {
-	case input == "q" || input == "quit" || input == "exit":
-		return true
-	case input == "r" || input == "return":
-		runCLI()
-		return true
-	case strings.HasPrefix(input, "add "):
-		handleAddTask(strings.TrimSpace(input[4:]), taskList, taskFile)
-	case strings.HasPrefix(input, "a "):
-		handleAddTask(strings.TrimSpace(input[2:]), taskList, taskFile)
-	case strings.HasPrefix(input, "remove "):
-		handleRemoveTask(strings.TrimSpace(input[7:]), taskList, taskFile)
-	case strings.HasPrefix(input, "r "):
-		handleRemoveTask(strings.TrimSpace(input[2:]), taskList, taskFile)
-	case strings.HasPrefix(input, "done "):
-		handleDoneTask(strings.TrimSpace(input[5:]), taskList, taskFile)
-	case strings.HasPrefix(input, "d "):
-		handleDoneTask(strings.TrimSpace(input[2:]), taskList, taskFile)
-	default:
-		if taskNum, err := strconv.Atoi(input); err == nil {
-			handleToggleTimer(taskNum, taskList, taskFile)
-		} else {
-			fmt.Println("[!] Invalid command. Type a number, 'add / a <task>', 'remove / r <number>', 'done / d <number>', 'r' to return, or 'q' to quit")
+func handleList(config *Config, cli *cliArgs) error {
+	format := cli.flag("format")
+	if cli.has("json") {
+		format = formatJSON
+	}
+
+	store, listName, err := openResolvedList(config, cli)
+	if err != nil {
+		return err
+	}
+
+	taskList, err := store.Load(listName)
+	if err != nil {
+		return fmt.Errorf("load error: %v", err)
+	}
+
+	filter, err := parseFilter(cli.args)
+	if err != nil {
+		return err
+	}
+
+	if format == "" || format == formatText {
+		for _, warning := range staleWarnings(config, taskList) {
+			fmt.Println(warning)
 		}
 	}
-	return false
+	return writeTaskList(os.Stdout, taskList, listName, format, filter)
 }
 
-func handleAddTask(taskTitle string, taskList *TaskList, taskFile string) {
+func handleAdd(config *Config, cli *cliArgs) error {
+	taskTitle := strings.TrimSpace(strings.Join(cli.args, " "))
 	if taskTitle == "" {
-		fmt.Println("[!] Task title cannot be empty")
-		return
+		return fmt.Errorf("task title cannot be empty")
 	}
 
-	addTask(taskList, taskTitle)
-	if err := saveTasks(taskFile, taskList); err != nil {
-		fmt.Printf("[!] Save error: %v\n", err)
+	return updateResolvedList(config, cli, func(taskList *TaskList) error {
+		return addTask(taskList, taskTitle)
+	})
+}
+
+func handleStartTask(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("task number or ID required")
 	}
+
+	store, listName, err := openResolvedList(config, cli)
+	if err != nil {
+		return err
+	}
+	taskList, err := store.Load(listName)
+	if err != nil {
+		return fmt.Errorf("load error: %v", err)
+	}
+
+	return updateTimer(config, store, listName, taskList, func(taskList *TaskList) error {
+		if err := checkStaleTimers(config, taskList); err != nil {
+			return err
+		}
+		if strings.Contains(cli.args[0], ".") {
+			index, child, err := resolveSubtaskRef(taskList, cli.args[0])
+			if err != nil {
+				return err
+			}
+			return toggleSubtaskTimer(taskList, index, child)
+		}
+		index, err := resolveTaskRef(taskList, cli.args[0])
+		if err != nil {
+			return err
+		}
+		return toggleTaskTimer(taskList, index)
+	})
 }
 
-func handleRemoveTask(taskNumStr string, taskList *TaskList, taskFile string) {
-	taskNum, err := strconv.Atoi(taskNumStr)
+// handlePomodoro runs focus blocks and breaks on a task until Enter is
+// pressed, with the countdown on one line.
+func handlePomodoro(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("task number or ID required")
+	}
+
+	store, listName, err := openResolvedList(config, cli)
 	if err != nil {
-		fmt.Printf("[!] '%s' is not a valid number\n", taskNumStr)
-		return
+		return err
+	}
+	taskList, err := store.Load(listName)
+	if err != nil {
+		return fmt.Errorf("load error: %v", err)
 	}
 
-	if err := removeTask(taskList, taskNum); err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+	if err := checkStaleTimers(config, taskList); err != nil {
+		return err
+	}
+	pomo, err := newPomodoro(config, store, listName, taskList, cli.args[0], func(footer string) {
+		fmt.Printf("\r%s\033[K", footer)
+	})
+	if err != nil {
+		return err
 	}
+	return pomo.run()
+}
 
-	if err := saveTasks(taskFile, taskList); err != nil {
-		fmt.Printf("[!] Save error: %v\n", err)
+func handleStatus(config *Config, cli *cliArgs) error {
+	format := cli.flag("format")
+	if cli.has("json") {
+		format = formatJSON
 	}
+	return showStatus(config, format)
 }
 
-func handleDoneTask(taskNumStr string, taskList *TaskList, taskFile string) {
-	taskNum, err := strconv.Atoi(taskNumStr)
-	if err != nil {
-		fmt.Printf("[!] '%s' is not a valid number\n", taskNumStr)
-		return
+func handleSub(config *Config, cli *cliArgs) error {
+	title := strings.Join(cli.args[min(1, len(cli.args)):], " ")
+	return updateTaskFromArgs(config, cli, func(taskList *TaskList, index int) error {
+		return addSubtask(taskList, index, title)
+	})
+}
+
+func handleMarkDone(config *Config, cli *cliArgs) error {
+	return updateTaskFromArgs(config, cli, stopsTimers(config, markTaskComplete))
+}
+
+// handleHistory prints the status changes of a task or subtask.
+func handleHistory(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("task number or ID required")
 	}
 
-	if err := markTaskComplete(taskList, taskNum); err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+	store, listName, err := openResolvedList(config, cli)
+	if err != nil {
+		return err
+	}
+	taskList, err := store.Load(listName)
+	if err != nil {
+		return fmt.Errorf("load error: %v", err)
+	}
+	task, err := resolveAnyTask(taskList, cli.args[0])
+	if err != nil {
+		return err
 	}
+	for _, line := range getHistoryLines(task) {
+		fmt.Println(line)
+	}
+	return nil
+}
 
-	if err := saveTasks(taskFile, taskList); err != nil {
-		fmt.Printf("[!] Save error: %v\n", err)
+// handleLog records time on a task: "tgo log <task> 45m" or
+// "tgo log <task> [day] 09:00-10:30".
+func handleLog(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("task number or ID required")
 	}
+	start, end, err := parseLogArgs(cli.args[1:], time.Now())
+	if err != nil {
+		return err
+	}
+	return updateTrackedTaskFromArgs(config, cli, func(taskList *TaskList, others []*TaskList, task *Task) error {
+		return logTime(taskList, others, task, start, end)
+	})
 }
 
-func handleToggleTimer(taskNum int, taskList *TaskList, taskFile string) {
-	if err := toggleTaskTimer(taskList, taskNum); err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+// handleSessions lists the sessions of a task, or changes one of them:
+// "tgo sessions <task> <n> set|split|rm ...".
+func handleSessions(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("task number or ID required")
+	}
+	if len(cli.args) > 1 {
+		return updateTrackedTaskFromArgs(config, cli, func(taskList *TaskList, others []*TaskList, task *Task) error {
+			return changeSession(taskList, others, task, cli.args[1:])
+		})
 	}
 
-	if err := saveTasks(taskFile, taskList); err != nil {
-		fmt.Printf("[!] Save error: %v\n", err)
+	store, listName, err := openResolvedList(config, cli)
+	if err != nil {
+		return err
+	}
+	taskList, err := store.Load(listName)
+	if err != nil {
+		return fmt.Errorf("load error: %v", err)
 	}
+	task, err := resolveAnyTask(taskList, cli.args[0])
+	if err != nil {
+		return err
+	}
+	for _, line := range getSessionLines(task, time.Now()) {
+		fmt.Println(line)
+	}
+	return nil
 }
 
-func handleSetFolder(config *Config) {
-	if len(os.Args) < 3 {
-		fmt.Println("[!] Folder path required")
-		return
+// handleRecover lists stale timers, or resolves one:
+// "tgo recover <task> keep|last|at [day] <time>|discard".
+func handleRecover(config *Config, cli *cliArgs) error {
+	if len(cli.args) > 0 {
+		action, at, err := parseRecoverArgs(cli.args[1:], time.Now())
+		if err != nil {
+			return err
+		}
+		return updateTrackedTaskFromArgs(config, cli, func(taskList *TaskList, others []*TaskList, task *Task) error {
+			return recoverTimer(taskList, others, task, action, at)
+		})
 	}
 
-	folder := os.Args[2]
-	if strings.HasPrefix(folder, "~/") {
-		home, _ := os.UserHomeDir()
-		folder = filepath.Join(home, folder[2:])
+	store, listName, err := openResolvedList(config, cli)
+	if err != nil {
+		return err
+	}
+	taskList, err := store.Load(listName)
+	if err != nil {
+		return fmt.Errorf("load error: %v", err)
+	}
+	warnings := staleWarnings(config, taskList)
+	if len(warnings) == 0 {
+		fmt.Println("[i] No stale timers")
 	}
+	for _, warning := range warnings {
+		fmt.Println(warning)
+	}
+	return nil
+}
 
-	absDir, err := filepath.Abs(folder)
+func handleTag(config *Config, cli *cliArgs, op func(*TaskList, int, []string) error) error {
+	tags, err := parseTagArgs(cli.args[min(1, len(cli.args)):])
 	if err != nil {
-		fmt.Printf("[!] Invalid path: %v\n", err)
-		return
+		return err
 	}
+	return updateTaskFromArgs(config, cli, func(taskList *TaskList, index int) error {
+		return op(taskList, index, tags)
+	})
+}
 
-	if _, err := os.Stat(absDir); os.IsNotExist(err) {
-		fmt.Printf("[!] Directory not found: %s\n", absDir)
-		return
+// handleWait puts a task on hold: "tgo wait <task> [note] [until <when>]".
+// '-' takes it off hold again.
+func handleWait(config *Config, cli *cliArgs) error {
+	args := strings.Join(cli.args[min(1, len(cli.args)):], " ")
+	if strings.TrimSpace(args) == "-" {
+		return updateTaskFromArgs(config, cli, stopWaiting)
 	}
 
-	config.TaskDir = absDir
-	if err := saveConfig(config); err != nil {
-		fmt.Printf("[!] Save error: %v\n", err)
-		return
+	note, followUp, err := parseWaitArgs(args)
+	if err != nil {
+		return err
 	}
+	return updateTaskFromArgs(config, cli, stopsTimers(config, func(taskList *TaskList, index int) error {
+		return waitTask(taskList, index, note, followUp)
+	}))
+}
 
-	fmt.Printf("[+] Task directory set: %s\n", absDir)
-	showDirContents(absDir)
+func handleEdit(config *Config, cli *cliArgs) error {
+	title := strings.Join(cli.args[min(1, len(cli.args)):], " ")
+	return updateTaskFromArgs(config, cli, func(taskList *TaskList, index int) error {
+		return editTaskTitle(taskList, index, title)
+	})
 }
 
-func handleCreateList(config *Config) {
-	if config.TaskDir == "" {
-		fmt.Println("[!] No task directory configured")
-		fmt.Println("Use: tgo set-dir <path>")
-		return
+// handleDue sets the due date of a task, or without arguments lists the
+// open tasks with a due date across all lists.
+func handleDue(config *Config, cli *cliArgs) error {
+	if len(cli.args) == 0 {
+		return showDueTasks(config, cli.flag("list"))
+	}
+	if len(cli.args) < 2 {
+		return fmt.Errorf("due date required, e.g. tomorrow, fri 17:00, 2026-11-03 or +3d ('-' clears)")
 	}
 
-	var listName string
-	if len(os.Args) < 3 {
-		fmt.Print("Enter list name: ")
-		scanner := bufio.NewScanner(os.Stdin)
-		if scanner.Scan() {
-			listName = strings.TrimSpace(scanner.Text())
-		}
-		if listName == "" {
-			fmt.Println("[!] List name cannot be empty")
-			return
+	due, err := parseDueArg(strings.Join(cli.args[1:], " "))
+	if err != nil {
+		return err
+	}
+	return updateTaskFromArgs(config, cli, func(taskList *TaskList, index int) error {
+		return setTaskDue(taskList, index, due)
+	})
+}
+
+func handleRepeat(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 2 {
+		return fmt.Errorf("usage: tgo repeat <task> <daily|weekdays|weekly [days]|monthly|every <n>d|->")
+	}
+	rule, err := parseRecurrenceArg(strings.Join(cli.args[1:], " "))
+	if err != nil {
+		return err
+	}
+	return updateTaskFromArgs(config, cli, func(taskList *TaskList, index int) error {
+		return setTaskRecurrence(taskList, index, rule)
+	})
+}
+
+// handleDependency links or unlinks "tgo dep <task> <depends-on>".
+func handleDependency(config *Config, cli *cliArgs, op func(taskList *TaskList, index, depIndex int) error) error {
+	if len(cli.args) < 2 {
+		return fmt.Errorf("usage: tgo %s <task> <depends-on task>", cli.command)
+	}
+	return updateTaskFromArgs(config, cli, func(taskList *TaskList, index int) error {
+		depIndex, err := resolveTaskRef(taskList, cli.args[1])
+		if err != nil {
+			return err
 		}
-	} else {
-		listName = strings.Join(os.Args[2:], " ")
+		return op(taskList, index, depIndex)
+	})
+}
+
+func handleMove(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 2 {
+		return fmt.Errorf("usage: tgo move <task> <position>")
 	}
+	pos, err := strconv.Atoi(cli.args[1])
+	if err != nil {
+		return fmt.Errorf("invalid position '%s'", cli.args[1])
+	}
+	return updateTaskFromArgs(config, cli, func(taskList *TaskList, index int) error {
+		return moveTask(taskList, index, pos)
+	})
+}
 
-	if err := createNewList(config.TaskDir, listName); err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+// handleMoveToList moves a task to another list. --list picks the list it
+// is moved from.
+func handleMoveToList(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 2 {
+		return fmt.Errorf("usage: tgo mv <task> <list>")
 	}
 
-	fmt.Printf("[+] Created list: %s\n", listName)
-	showDirContents(config.TaskDir)
+	store, listName, err := openResolvedList(config, cli)
+	if err != nil {
+		return err
+	}
+	taskList, err := store.Load(listName)
+	if err != nil {
+		return fmt.Errorf("load error: %v", err)
+	}
+	taskID, err := resolveTaskID(taskList, cli.args[0])
+	if err != nil {
+		return err
+	}
+	return transferTask(store, listName, taskList, taskID, strings.Join(cli.args[1:], " "))
 }
 
-func handleRemoveList(config *Config) {
-	if config.TaskDir == "" {
-		fmt.Println("[!] No task directory configured")
-		fmt.Println("Use: tgo set-dir <path>")
-		return
+func handlePriority(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 2 {
+		return fmt.Errorf("usage: tgo prio <task> <H|M|L|->")
+	}
+	priority, err := parsePriority(cli.args[1])
+	if err != nil {
+		return err
 	}
+	return updateTaskFromArgs(config, cli, func(taskList *TaskList, index int) error {
+		return setTaskPriority(taskList, index, priority)
+	})
+}
 
-	taskFiles, err := findTaskFiles(config.TaskDir)
+// handleSort sets how the list is sorted, or shows the current sort.
+func handleSort(config *Config, cli *cliArgs) error {
+	if len(cli.args) == 0 {
+		store, listName, err := openResolvedList(config, cli)
+		if err != nil {
+			return err
+		}
+		taskList, err := store.Load(listName)
+		if err != nil {
+			return fmt.Errorf("load error: %v", err)
+		}
+		fmt.Printf("[i] %s is sorted by %s (%s)\n", listName, taskList.sortMode(), strings.Join(sortModes, ", "))
+		return nil
+	}
+
+	mode, err := parseSortMode(cli.args[0])
 	if err != nil {
-		fmt.Printf("[!] %v\n", err)
-		showDirContents(config.TaskDir)
-		return
+		return err
 	}
+	return updateResolvedList(config, cli, func(taskList *TaskList) error {
+		setSortMode(taskList, mode)
+		return nil
+	})
+}
 
-	if len(taskFiles) == 1 {
-		fmt.Printf("Remove '%s'? (y/N): ", taskFiles[0])
-		scanner := bufio.NewScanner(os.Stdin)
-		if scanner.Scan() && strings.ToLower(scanner.Text()) == "y" {
-			if err := os.Remove(filepath.Join(config.TaskDir, taskFiles[0])); err != nil {
-				fmt.Printf("[!] Failed to remove: %v\n", err)
-				return
-			}
-			fmt.Printf("[-] Removed: %s\n", taskFiles[0])
+// handleNote sets the comment of a task. Without text the comment is opened
+// in $EDITOR, '-' clears it.
+func handleNote(config *Config, cli *cliArgs) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("task number or ID required")
+	}
+
+	comment := strings.Join(cli.args[1:], " ")
+	if comment == "-" {
+		comment = ""
+	} else if comment == "" {
+		store, listName, err := openResolvedList(config, cli)
+		if err != nil {
+			return err
 		}
-		return
+		taskList, err := store.Load(listName)
+		if err != nil {
+			return fmt.Errorf("load error: %v", err)
+		}
+		index, err := resolveTaskRef(taskList, cli.args[0])
+		if err != nil {
+			return err
+		}
+		if comment, err = editText(taskList.Items[index-1].Comment); err != nil {
+			return err
+		}
+		// Find the task by ID again, the list may change while the editor is open.
+		taskID := taskList.Items[index-1].ID
+		return updateTasks(store, listName, taskList, func(taskList *TaskList) error {
+			index, err := findTaskIndex(taskList, taskID)
+			if err != nil {
+				return err
+			}
+			return setTaskComment(taskList, index, comment)
+		})
 	}
 
-	fmt.Printf("[i] Found %d task lists:\n\n", len(taskFiles))
-	for i, file := range taskFiles {
-		displayName := strings.TrimSuffix(file, ".json")
-		fmt.Printf("%d. %s\n", i+1, displayName)
+	return updateTaskFromArgs(config, cli, func(taskList *TaskList, index int) error {
+		return setTaskComment(taskList, index, comment)
+	})
+}
+
+// updateTaskFromArgs runs op on the task the first argument refers to.
+func updateTaskFromArgs(config *Config, cli *cliArgs, op func(taskList *TaskList, index int) error) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("task number or ID required")
 	}
 
-	fmt.Printf("\nSelect list to remove (1-%d): ", len(taskFiles))
-	var choice int
-	if _, err := fmt.Scanf("%d", &choice); err != nil || choice < 1 || choice > len(taskFiles) {
-		fmt.Println("[!] Invalid selection")
-		return
+	return updateResolvedList(config, cli, func(taskList *TaskList) error {
+		index, err := resolveTaskRef(taskList, cli.args[0])
+		if err != nil {
+			return err
+		}
+		return op(taskList, index)
+	})
+}
+
+// updateAnyTaskFromArgs is updateTaskFromArgs for a task or a <task>.<n>
+// subtask.
+func updateAnyTaskFromArgs(config *Config, cli *cliArgs, op func(taskList *TaskList, task *Task) error) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("task number or ID required")
 	}
 
-	selectedFile := taskFiles[choice-1]
-	fmt.Printf("Remove '%s'? (y/N): ", selectedFile)
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() && strings.ToLower(scanner.Text()) == "y" {
-		if err := os.Remove(filepath.Join(config.TaskDir, selectedFile)); err != nil {
-			fmt.Printf("[!] Failed to remove: %v\n", err)
-			return
+	return updateResolvedList(config, cli, func(taskList *TaskList) error {
+		task, err := resolveAnyTask(taskList, cli.args[0])
+		if err != nil {
+			return err
 		}
-		fmt.Printf("[-] Removed: %s\n", selectedFile)
+		return op(taskList, task)
+	})
+}
+
+// updateTrackedTaskFromArgs is updateAnyTaskFromArgs for an operation on
+// tracked time, which gets the other lists of the store as well to check for
+// overlapping sessions.
+func updateTrackedTaskFromArgs(config *Config, cli *cliArgs, op func(taskList *TaskList, others []*TaskList, task *Task) error) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("task number or ID required")
+	}
+
+	store, listName, err := openResolvedList(config, cli)
+	if err != nil {
+		return err
+	}
+	others, err := loadOtherLists(store, listName)
+	if err != nil {
+		return err
+	}
+	taskList, err := store.Load(listName)
+	if err != nil {
+		return fmt.Errorf("load error: %v", err)
 	}
+
+	return updateTasks(store, listName, taskList, func(taskList *TaskList) error {
+		task, err := resolveAnyTask(taskList, cli.args[0])
+		if err != nil {
+			return err
+		}
+		return op(taskList, others, task)
+	})
 }
 
-func handleStartTask(config *Config) {
-	if len(os.Args) < 3 {
-		fmt.Println("[!] Task number required")
-		return
+// updateSubtaskFromArgs is updateTaskFromArgs for a <task>.<n> subtask.
+func updateSubtaskFromArgs(config *Config, cli *cliArgs, op func(taskList *TaskList, index, child int) error) error {
+	if len(cli.args) < 1 {
+		return fmt.Errorf("subtask required, e.g. 3.2")
 	}
 
-	taskNum, err := strconv.Atoi(os.Args[2])
+	return updateResolvedList(config, cli, func(taskList *TaskList) error {
+		index, child, err := resolveSubtaskRef(taskList, cli.args[0])
+		if err != nil {
+			return err
+		}
+		return op(taskList, index, child)
+	})
+}
+
+// updateResolvedList loads the list the command line points at, applies op
+// and saves it.
+func updateResolvedList(config *Config, cli *cliArgs, op func(*TaskList) error) error {
+	store, listName, err := openResolvedList(config, cli)
 	if err != nil {
-		fmt.Printf("[!] '%s' is not a valid number\n", os.Args[2])
-		return
+		return err
 	}
 
-	if config.TaskDir == "" {
-		fmt.Println("[!] No task directory configured")
-		return
+	taskList, err := store.Load(listName)
+	if err != nil {
+		return fmt.Errorf("load error: %v", err)
 	}
 
-	taskFiles, err := findTaskFiles(config.TaskDir)
+	return updateTasks(store, listName, taskList, op)
+}
+
+// openResolvedList opens the store and resolves the list a one-shot command
+// works on, remembering it as the last used list.
+func openResolvedList(config *Config, cli *cliArgs) (Store, string, error) {
+	store, err := openStore(config)
 	if err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+		return nil, "", err
 	}
 
-	taskFile, err := selectTaskFile(config.TaskDir, taskFiles)
+	listName, err := resolveList(config, store, cli.requestedList())
 	if err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+		return nil, "", err
 	}
+	rememberList(config, listName)
+	return store, listName, nil
+}
 
-	taskList, err := loadTasks(taskFile)
+func handleMergeConflicts(config *Config) error {
+	store, err := openStore(config)
 	if err != nil {
-		fmt.Printf("[!] Load error: %v\n", err)
-		return
+		return err
+	}
+	js, ok := store.(*jsonStore)
+	if !ok {
+		fmt.Println("[i] Sync conflicts only apply to the json store")
+		return nil
 	}
 
-	if err := toggleTaskTimer(taskList, taskNum); err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+	merged, err := js.mergeConflicts()
+	if err != nil {
+		return err
 	}
 
-	if err := saveTasks(taskFile, taskList); err != nil {
-		fmt.Printf("[!] Save error: %v\n", err)
+	left := countConflictFiles(config.TaskDir)
+	if merged == 0 && left == 0 {
+		fmt.Println("[i] No sync conflicts found")
+		return nil
+	}
+	if merged > 0 {
+		fmt.Printf("[i] Merged %d conflict copies, originals archived in %s\n",
+			merged, filepath.Join(config.TaskDir, conflictArchiveDir))
 	}
+	if left > 0 {
+		fmt.Printf("[!] %d conflict copies left\n", left)
+	}
+	return nil
 }
 
-func handleMarkDone(config *Config) {
-	if len(os.Args) < 3 {
-		fmt.Println("[!] Task number required")
-		return
+func handleConfig(config *Config, cli *cliArgs) error {
+	if len(cli.args) == 0 {
+		for _, setting := range configSettings {
+			fmt.Printf("  %-16s %s\n", setting.key, setting.get(config))
+		}
+		return nil
 	}
 
-	taskNum, err := strconv.Atoi(os.Args[2])
+	setting, err := findConfigSetting(cli.args[0])
 	if err != nil {
-		fmt.Printf("[!] '%s' is not a valid number\n", os.Args[2])
-		return
+		return err
+	}
+	if len(cli.args) == 1 {
+		fmt.Println(setting.get(config))
+		return nil
 	}
 
-	if config.TaskDir == "" {
-		fmt.Println("[!] No task directory configured")
-		return
+	updated := *config
+	if err := setting.set(&updated, strings.Join(cli.args[1:], " ")); err != nil {
+		return err
+	}
+	// Bring the lists along, otherwise switching stores looks like they're
+	// gone. The store only changes once they all made it.
+	previous := setting.get(config)
+	if setting.key == "store" && previous != setting.get(&updated) && config.TaskDir != "" {
+		if err := importFromStore(&updated, previous); err != nil {
+			return fmt.Errorf("%v, store is still %s", err, previous)
+		}
 	}
 
-	taskFiles, err := findTaskFiles(config.TaskDir)
-	if err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+	*config = updated
+	if err := saveConfig(config); err != nil {
+		return fmt.Errorf("save error: %v", err)
 	}
+	fmt.Printf("[+] %s set: %s\n", setting.key, setting.get(config))
+	return nil
+}
 
-	taskFile, err := selectTaskFile(config.TaskDir, taskFiles)
+// handleImport copies the lists of the other store into the configured one:
+// "tgo import json" or "tgo import sqlite".
+func handleImport(config *Config, cli *cliArgs) error {
+	if len(cli.args) != 1 {
+		return fmt.Errorf("store to import from required: %s or %s", storeJSON, storeSQLite)
+	}
+	current := config.Store
+	if current == "" {
+		current = storeJSON
+	}
+	if cli.args[0] == current {
+		return fmt.Errorf("%s is the store in use, import from the other one", current)
+	}
+	return importFromStore(config, cli.args[0])
+}
+
+// importFromStore copies the lists of the from store into the one config
+// points at. A tgo.db created for the import is removed again if it fails.
+func importFromStore(config *Config, from string) error {
+	dbPath := filepath.Join(config.TaskDir, sqliteFileName)
+	_, statErr := os.Stat(dbPath)
+	dbExisted := statErr == nil
+	if from == storeSQLite && !dbExisted {
+		return fmt.Errorf("nothing to import, %s doesn't exist", dbPath)
+	}
+
+	source := *config
+	source.Store = from
+	fromStore, err := openStore(&source)
 	if err != nil {
-		fmt.Printf("[!] %v\n", err)
-		return
+		return err
+	}
+	toStore, err := openStore(config)
+	if err != nil {
+		return err
 	}
 
-	taskList, err := loadTasks(taskFile)
+	imported, err := importLists(fromStore, toStore)
 	if err != nil {
-		fmt.Printf("[!] Load error: %v\n", err)
-		return
+		if db, ok := toStore.(*sqliteStore); ok && !dbExisted {
+			db.Close()
+			for _, suffix := range []string{"", "-wal", "-shm"} {
+				os.Remove(dbPath + suffix)
+			}
+		}
+		return err
 	}
+	fmt.Printf("[i] Imported %d lists from the %s store\n", imported, from)
+	return nil
+}
 
-	if err := markTaskComplete(taskList, taskNum); err != nil {
-		fmt.Printf("[!] %v\n", err)
+// showLists prints what the configured store holds after a list was added
+// or removed.
+func showLists(config *Config, store Store) {
+	if _, ok := store.(*jsonStore); ok {
+		showDirContents(config.TaskDir)
 		return
 	}
 
-	if err := saveTasks(taskFile, taskList); err != nil {
-		fmt.Printf("[!] Save error: %v\n", err)
+	listNames, err := store.Lists()
+	if err != nil {
+		return
 	}
+	displayTaskLists(listNames)
+	fmt.Println()
 }
 
 func clearScreen() {
 	fmt.Print("\033[2J\033[H")
 }
-
-func handleCreateFirstList(config *Config) {
-	fmt.Print("Enter your first list name: ")
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() {
-		listName := strings.TrimSpace(scanner.Text())
-		if listName == "" {
-			fmt.Println("[!] List name cannot be empty")
-			return
-		}
-
-		if err := createNewList(config.TaskDir, listName); err != nil {
-			fmt.Printf("[!] %v\n", err)
-			return
-		}
-
-		fmt.Printf("[+] Created your first list: %s\n", listName)
-		fmt.Println("[>] Starting interactive mode...")
-		time.Sleep(time.Second)
-		runInteractiveMode(config)
-	}
-}
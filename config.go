@@ -2,8 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 const configFile = ".task-cli-config.json"
@@ -36,5 +39,148 @@ func saveConfig(config *Config) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(configPath, data, 0644)
+	return writeFileAtomic(configPath, data, 0644, false)
+}
+
+// configSetting is a Config field that can be changed with 'tgo config'.
+type configSetting struct {
+	key string
+	get func(config *Config) string
+	set func(config *Config, value string) error
+}
+
+var configSettings = []configSetting{
+	{
+		key: "store",
+		get: func(config *Config) string {
+			if config.Store == "" {
+				return storeJSON
+			}
+			return config.Store
+		},
+		set: func(config *Config, value string) error {
+			switch value {
+			case storeJSON, storeSQLite:
+				config.Store = value
+				return nil
+			}
+			return fmt.Errorf("store must be %s or %s", storeJSON, storeSQLite)
+		},
+	},
+	{
+		key: "default-list",
+		get: func(config *Config) string {
+			return config.DefaultList
+		},
+		set: func(config *Config, value string) error {
+			if value == "-" {
+				config.DefaultList = ""
+				return nil
+			}
+			name, err := listNameFromTitle(value)
+			if err != nil {
+				return err
+			}
+			config.DefaultList = name
+			return nil
+		},
+	},
+	durationSetting("stale-after", func(c *Config) *string { return &c.StaleAfter }, defaultStaleAfter, true),
+	durationSetting("idle-after", func(c *Config) *string { return &c.IdleAfter }, defaultIdleAfter, true),
+	{
+		key: "single-timer",
+		get: func(config *Config) string {
+			if config.SingleTimer {
+				return "on"
+			}
+			return "off"
+		},
+		set: func(config *Config, value string) error {
+			switch value {
+			case "on":
+				config.SingleTimer = true
+				return nil
+			case "off":
+				config.SingleTimer = false
+				return nil
+			}
+			return fmt.Errorf("single-timer must be on or off")
+		},
+	},
+	durationSetting("pomo-focus", func(c *Config) *string { return &c.PomoFocus }, defaultPomoFocus, false),
+	durationSetting("pomo-break", func(c *Config) *string { return &c.PomoBreak }, defaultPomoBreak, false),
+	durationSetting("pomo-long-break", func(c *Config) *string { return &c.PomoLongBreak }, defaultPomoLongBreak, false),
+}
+
+// durationSetting is a setting holding a duration, '-' resets it to def.
+// With canTurnOff it also takes 'off', which settingDuration reads as 0.
+func durationSetting(key string, field func(*Config) *string, def time.Duration, canTurnOff bool) configSetting {
+	return configSetting{
+		key: key,
+		get: func(config *Config) string {
+			d := settingDuration(*field(config), def, canTurnOff)
+			if d == 0 {
+				return "off"
+			}
+			return d.String()
+		},
+		set: func(config *Config, value string) error {
+			switch {
+			case value == "-":
+				*field(config) = ""
+				return nil
+			case value == "off" && canTurnOff:
+				*field(config) = value
+				return nil
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				if canTurnOff {
+					return fmt.Errorf("%s must be a duration like %s, 'off' or '-' for the default", key, def)
+				}
+				return fmt.Errorf("%s must be a duration like %s, or '-' for the default", key, def)
+			}
+			*field(config) = d.String()
+			return nil
+		},
+	}
+}
+
+// settingDuration reads the value of a duration setting: def when it is
+// unset or invalid, 0 when it is 'off' and canTurnOff.
+func settingDuration(value string, def time.Duration, canTurnOff bool) time.Duration {
+	if value == "off" && canTurnOff {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+func findConfigSetting(key string) (*configSetting, error) {
+	for i := range configSettings {
+		if configSettings[i].key == key {
+			return &configSettings[i], nil
+		}
+	}
+
+	keys := make([]string, len(configSettings))
+	for i, setting := range configSettings {
+		keys[i] = setting.key
+	}
+	return nil, fmt.Errorf("unknown setting '%s', use one of: %s", key, strings.Join(keys, ", "))
+}
+
+// rememberList records listName as the last used list, so later commands
+// without --list go to the same list.
+func rememberList(config *Config, listName string) {
+	if config.LastList == listName {
+		return
+	}
+	config.LastList = listName
+	if err := saveConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "[!] Cannot remember last list: %v\n", err)
+	}
 }
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const conflictArchiveDir = ".tgo-conflicts"
+
+// Finding the version two conflicting copies forked from: every save records
+// in SyncBase the version of another device the list was last built on, and
+// each device keeps the versions it wrote or got from the others, by hash.
+// That folder is outside the task folder, so it is never synced.
+const (
+	syncStateDir = ".task-cli-sync"
+	// keptVersions is how many versions of a list are kept per device.
+	keptVersions = 30
+)
+
+func (s *jsonStore) syncStatePath(name string) string {
+	home, _ := os.UserHomeDir()
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		dir = s.dir
+	}
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(home, syncStateDir, hex.EncodeToString(sum[:8]), name)
+}
+
+func (s *jsonStore) versionPath(name, hash string) string {
+	return filepath.Join(s.syncStatePath(name), hash+".json")
+}
+
+// savedPath holds the hash of the version of the list this device saved last.
+func (s *jsonStore) savedPath(name string) string {
+	return filepath.Join(s.syncStatePath(name), "saved")
+}
+
+// rememberVersion keeps data, the list as it is on disk, dropping the oldest
+// versions beyond keptVersions. Errors are ignored: without the version a
+// later merge only has no base.
+func (s *jsonStore) rememberVersion(name string, hash string, data []byte) {
+	dir := s.syncStatePath(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	if err := writeFileAtomic(s.versionPath(name, hash), data, 0644, false); err != nil {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type version struct {
+		path    string
+		modTime time.Time
+	}
+	var versions []version
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		versions = append(versions, version{filepath.Join(dir, entry.Name()), info.ModTime()})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].modTime.After(versions[j].modTime)
+	})
+	for i := keptVersions; i < len(versions); i++ {
+		os.Remove(versions[i].path)
+	}
+}
+
+// rememberSave records data as the version of the list this device wrote.
+func (s *jsonStore) rememberSave(name string, data []byte) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	s.rememberVersion(name, hash, data)
+	writeFileAtomic(s.savedPath(name), []byte(hash), 0644, false)
+}
+
+// rememberLoad notices a version of the list written by another device: it
+// is kept and becomes the SyncBase of the next save. While the list has
+// conflict copies nothing changes, the file may hold either side.
+func (s *jsonStore) rememberLoad(name string, taskList *TaskList) {
+	if taskList.source == nil {
+		return
+	}
+	hash := hex.EncodeToString(taskList.source.hash[:])
+	if saved, err := os.ReadFile(s.savedPath(name)); err == nil && string(saved) == hash {
+		return
+	}
+	if conflicts, err := findConflictFiles(s.dir); err != nil || len(conflicts[name+".json"]) > 0 {
+		return
+	}
+
+	data, err := json.MarshalIndent(taskList, "", "  ")
+	if err != nil {
+		return
+	}
+	s.rememberVersion(name, hash, data)
+	taskList.SyncBase = hash
+}
+
+// findBase returns the version ours and theirs both descend from, the newer
+// of the SyncBase of either that this device still has. nil if there is none.
+func (s *jsonStore) findBase(name string, ours, theirs *TaskList) *TaskList {
+	var base *TaskList
+	for _, hash := range []string{ours.SyncBase, theirs.SyncBase} {
+		if hash == "" {
+			continue
+		}
+		data, err := os.ReadFile(s.versionPath(name, hash))
+		if err != nil {
+			continue
+		}
+		version, err := decodeTaskList(data)
+		if err != nil {
+			continue
+		}
+		if base == nil || version.UpdatedAt.After(base.UpdatedAt) {
+			base = version
+		}
+	}
+	return base
+}
+
+func (s *jsonStore) forgetVersions(name string) {
+	os.RemoveAll(s.syncStatePath(name))
+}
+
+// Conflict copies produced by sync clients, the first group is the name of
+// the list they belong to.
+var conflictPatterns = []*regexp.Regexp{
+	// NextCloud, ownCloud, Dropbox: "work (conflicted copy 2026-10-01 101010).json"
+	regexp.MustCompile(`(?i)^(.+?) \([^()]*conflicted copy[^()]*\)\.json$`),
+	// Older ownCloud: "work_conflict-20261001-101010.json"
+	regexp.MustCompile(`^(.+?)_conflict-\d{8}-\d{6}\.json$`),
+	// Syncthing: "work.sync-conflict-20261001-101010-ABCDEFG.json"
+	regexp.MustCompile(`^(.+?)\.sync-conflict-[^.]*\.json$`),
+}
+
+// conflictTarget returns the task file a sync conflict copy belongs to.
+func conflictTarget(fileName string) (string, bool) {
+	for _, pattern := range conflictPatterns {
+		if m := pattern.FindStringSubmatch(fileName); m != nil {
+			return m[1] + ".json", true
+		}
+	}
+	return "", false
+}
+
+// findConflictFiles maps each task file to its conflict copies in folder.
+func findConflictFiles(folder string) (map[string][]string, error) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read folder %s: %v", folder, err)
+	}
+
+	conflicts := make(map[string][]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if target, ok := conflictTarget(entry.Name()); ok {
+			conflicts[target] = append(conflicts[target], entry.Name())
+		}
+	}
+	return conflicts, nil
+}
+
+func countConflictFiles(folder string) int {
+	conflicts, err := findConflictFiles(folder)
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, copies := range conflicts {
+		count += len(copies)
+	}
+	return count
+}
+
+// mergeConflicts merges every conflict copy in the store's folder into its
+// task file and moves the copy to the archive folder. It returns the number of
+// merged copies.
+func (s *jsonStore) mergeConflicts() (int, error) {
+	conflicts, err := findConflictFiles(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	targets := make([]string, 0, len(conflicts))
+	for target := range conflicts {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	merged := 0
+	for _, target := range targets {
+		n, err := s.mergeConflictCopies(target, conflicts[target])
+		merged += n
+		if err != nil {
+			return merged, fmt.Errorf("%s: %v", target, err)
+		}
+	}
+	return merged, nil
+}
+
+func (s *jsonStore) mergeConflictCopies(target string, copies []string) (int, error) {
+	folder := s.dir
+	listName := strings.TrimSuffix(target, ".json")
+	filePath := s.path(listName)
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		// Only the conflict copy survived, it becomes the list.
+		if err := os.Rename(filepath.Join(folder, copies[0]), filePath); err != nil {
+			return 0, err
+		}
+		fmt.Printf("[+] Restored %s from %s\n", target, copies[0])
+		copies = copies[1:]
+		if len(copies) == 0 {
+			return 1, nil
+		}
+	}
+
+	ours, err := loadTasks(filePath)
+	if err != nil {
+		return 0, err
+	}
+	var merged []string
+	err = updateTasks(s, listName, ours, func(ours *TaskList) error {
+		for _, copyName := range copies {
+			theirs, err := loadTasks(filepath.Join(folder, copyName))
+			if err != nil {
+				fmt.Printf("[!] Skipping %s: %v\n", copyName, err)
+				continue
+			}
+			// Without the version both forked from the merge is a union, a
+			// task missing on one side never counts as removed.
+			base := s.findBase(listName, ours, theirs)
+			if base == nil {
+				fmt.Printf("[i] No common version of %s and %s found, every difference counts as a conflict\n", target, copyName)
+			}
+			result, conflicts, err := mergeTaskLists(base, ours, theirs)
+			if err != nil {
+				return fmt.Errorf("cannot merge %s: %v", copyName, err)
+			}
+			*ours = *result
+
+			// A copy whose changes were dropped stays, nothing is lost
+			// without the user knowing.
+			keep := false
+			for _, conflict := range conflicts {
+				fmt.Printf("[!] Conflict in %s: %s\n", copyName, conflict)
+				keep = keep || conflict.theirsDropped
+			}
+			if keep {
+				fmt.Printf("[!] Kept %s: change the tasks by hand or delete the copy, then run 'tgo merge-conflicts' again\n", copyName)
+				continue
+			}
+			merged = append(merged, copyName)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, copyName := range merged {
+		if err := archiveConflictCopy(folder, copyName); err != nil {
+			return len(merged), err
+		}
+		fmt.Printf("[+] Merged %s into %s\n", copyName, target)
+	}
+	return len(merged), nil
+}
+
+func archiveConflictCopy(folder string, fileName string) error {
+	archive := filepath.Join(folder, conflictArchiveDir)
+	if err := os.MkdirAll(archive, 0755); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(folder, fileName), filepath.Join(archive, fileName))
+}
+
+// mergeConflict is a field both sides changed. The side with the newer
+// change wins, the other value is dropped.
+type mergeConflict struct {
+	task    string
+	field   string
+	kept    string
+	dropped string
+	// theirsDropped is set when the conflict copy's value was dropped.
+	theirsDropped bool
+}
+
+func (c mergeConflict) String() string {
+	side := "ours"
+	if !c.theirsDropped {
+		side = "theirs"
+	}
+	return fmt.Sprintf("'%s' %s: kept %s (%s), dropped %s", c.task, c.field, c.kept, side, c.dropped)
+}
+
+// mergeTaskLists does a three-way merge of two versions of a list, keyed by
+// Task.ID. A field changed on one side only takes that side's value, one
+// changed on both sides is a conflict decided by the newer change. Without a
+// base every difference is a conflict and no task counts as removed.
+func mergeTaskLists(base, ours, theirs *TaskList) (*TaskList, []mergeConflict, error) {
+	baseTasks := make(map[int64]*Task)
+	if base != nil {
+		for i := range base.Items {
+			baseTasks[base.Items[i].ID] = &base.Items[i]
+		}
+	}
+	theirTasks := make(map[int64]*Task)
+	for i := range theirs.Items {
+		theirTasks[theirs.Items[i].ID] = &theirs.Items[i]
+	}
+	ourTasks := make(map[int64]bool)
+
+	result := *ours
+	result.Items = []Task{}
+	if base != nil && ours.Title == base.Title {
+		result.Title = theirs.Title
+	}
+	if theirs.CreatedAt.Before(ours.CreatedAt) {
+		result.CreatedAt = theirs.CreatedAt
+	}
+
+	var conflicts []mergeConflict
+	for i := range ours.Items {
+		our := &ours.Items[i]
+		ourTasks[our.ID] = true
+		baseTask := baseTasks[our.ID]
+		their, ok := theirTasks[our.ID]
+		if !ok {
+			// Removed on their side and untouched on ours: keep it removed.
+			if baseTask != nil && sameTask(baseTask, our) {
+				continue
+			}
+			result.Items = append(result.Items, *our)
+			continue
+		}
+
+		task, taskConflicts, err := mergeTask(baseTask, our, their, !theirs.UpdatedAt.After(ours.UpdatedAt))
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Items = append(result.Items, task)
+		conflicts = append(conflicts, taskConflicts...)
+	}
+
+	for i := range theirs.Items {
+		their := &theirs.Items[i]
+		if ourTasks[their.ID] {
+			continue
+		}
+		// Removed on our side and untouched on theirs: keep it removed.
+		if baseTask := baseTasks[their.ID]; baseTask != nil && sameTask(baseTask, their) {
+			continue
+		}
+		result.Items = append(result.Items, *their)
+	}
+
+	return &result, conflicts, nil
+}
+
+// Fields that only make sense together and are merged as one.
+var statusFields = []string{"status", "active_start_time", "completed_at", "waiting_on", "follow_up_at"}
+
+// mergeTask merges two versions of the same task field by field: a field
+// changed on only one side takes that side's value, sessions and the status
+// history are unioned, the later LastActivity is kept. Of a field changed on
+// both sides the newer change wins: the last status change for the status
+// fields, else the newer list save, oursNewer (also on a tie). base may be
+// nil.
+// Working on the JSON form keeps fields this version doesn't know about.
+func mergeTask(base, ours, theirs *Task, oursNewer bool) (Task, []mergeConflict, error) {
+	ourFields, err := taskFields(ours)
+	if err != nil {
+		return Task{}, nil, err
+	}
+	theirFields, err := taskFields(theirs)
+	if err != nil {
+		return Task{}, nil, err
+	}
+	var baseFields map[string]json.RawMessage
+	if base != nil {
+		if baseFields, err = taskFields(base); err != nil {
+			return Task{}, nil, err
+		}
+	}
+
+	same := func(a, b map[string]json.RawMessage, fields []string) bool {
+		for _, field := range fields {
+			if !bytes.Equal(a[field], b[field]) {
+				return false
+			}
+		}
+		return true
+	}
+	take := func(fields []string) {
+		for _, field := range fields {
+			if value, ok := theirFields[field]; ok {
+				ourFields[field] = value
+			} else {
+				delete(ourFields, field)
+			}
+		}
+	}
+	show := func(fields map[string]json.RawMessage, field string) string {
+		if value, ok := fields[field]; ok {
+			return string(value)
+		}
+		return "(none)"
+	}
+
+	var conflicts []mergeConflict
+	merge := func(name string, fields []string, oursNewer bool) {
+		switch {
+		case same(ourFields, theirFields, fields):
+		case base != nil && same(ourFields, baseFields, fields):
+			take(fields)
+		case base != nil && same(theirFields, baseFields, fields):
+		default:
+			conflict := mergeConflict{task: ours.Title, field: name, theirsDropped: oursNewer}
+			if oursNewer {
+				conflict.kept, conflict.dropped = show(ourFields, fields[0]), show(theirFields, fields[0])
+			} else {
+				conflict.kept, conflict.dropped = show(theirFields, fields[0]), show(ourFields, fields[0])
+				take(fields)
+			}
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	ourChange, theirChange := lastStatusChange(ours), lastStatusChange(theirs)
+	merge("status", statusFields, ourChange.After(theirChange) || (ourChange.Equal(theirChange) && oursNewer))
+	fields := make(map[string]bool)
+	for field := range ourFields {
+		fields[field] = true
+	}
+	for field := range theirFields {
+		fields[field] = true
+	}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		if isStatusField(field) || field == "sessions" || field == "total_duration" || field == "history" || field == "last_activity" {
+			continue
+		}
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	for _, field := range names {
+		merge(field, []string{field}, oursNewer)
+	}
+
+	data, err := json.Marshal(ourFields)
+	if err != nil {
+		return Task{}, nil, err
+	}
+	var task Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return Task{}, nil, err
+	}
+
+	task.Sessions = mergeSessions(ours.Sessions, theirs.Sessions)
+	task.History = mergeHistory(ours.History, theirs.History)
+	if theirs.LastActivity != nil && (ours.LastActivity == nil || theirs.LastActivity.After(*ours.LastActivity)) {
+		task.LastActivity = theirs.LastActivity
+	}
+	task.recomputeDuration()
+	return task, conflicts, nil
+}
+
+// lastStatusChange is when the status of the task last changed, zero if it
+// never did.
+func lastStatusChange(task *Task) time.Time {
+	if len(task.History) == 0 {
+		return time.Time{}
+	}
+	return task.History[len(task.History)-1].At
+}
+
+func isStatusField(field string) bool {
+	for _, f := range statusFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func taskFields(task *Task) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+func sameTask(a, b *Task) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aData, bData)
+}
+
+// mergeSessions unions two session logs. Sessions starting at the same
+// instant are the same session, ours is kept.
+func mergeSessions(ours, theirs []Session) []Session {
+	seen := make(map[int64]bool)
+	merged := []Session{}
+	for _, sessions := range [][]Session{ours, theirs} {
+		for _, session := range sessions {
+			key := session.StartTime.UnixNano()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, session)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].StartTime.Before(merged[j].StartTime)
+	})
+	return merged
+}
+
+// mergeHistory unions two status histories, oldest change first.
+func mergeHistory(ours, theirs []StatusChange) []StatusChange {
+	type key struct {
+		at       int64
+		from, to TaskStatus
+	}
+	seen := make(map[key]bool)
+	var merged []StatusChange
+	for _, history := range [][]StatusChange{ours, theirs} {
+		for _, change := range history {
+			k := key{change.At.UnixNano(), change.From, change.To}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			merged = append(merged, change)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].At.Before(merged[j].At)
+	})
+	return merged
+}
+
+func conflictWarning(folder string) string {
+	count := countConflictFiles(folder)
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[!] %d sync conflict copies found, run 'tgo merge-conflicts'", count)
+}
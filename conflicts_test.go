@@ -0,0 +1,184 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeTaskLists(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	task := func(id int64, title string) Task {
+		return Task{ID: id, Title: title, Status: StatusPending, Sessions: []Session{}, CreatedAt: t0}
+	}
+	withSessions := func(task Task, starts ...int) Task {
+		for _, h := range starts {
+			start := t0.Add(time.Duration(h) * time.Hour)
+			task.Sessions = append(task.Sessions, newSession(start, start.Add(30*time.Minute)))
+		}
+		task.recomputeDuration()
+		return task
+	}
+	withComment := func(task Task, comment string) Task {
+		task.Comment = comment
+		return task
+	}
+	withStatus := func(task Task, status TaskStatus, h int) Task {
+		task.setStatus(status, t0.Add(time.Duration(h)*time.Hour))
+		return task
+	}
+	list := func(tasks ...Task) *TaskList {
+		return &TaskList{Title: "Work", Items: tasks, CreatedAt: t0, UpdatedAt: t0}
+	}
+	savedAt := func(taskList *TaskList, h int) *TaskList {
+		taskList.UpdatedAt = t0.Add(time.Duration(h) * time.Hour)
+		return taskList
+	}
+	// Done on our side, then cancelled on theirs: both changes stay in the
+	// history.
+	raced := withStatus(task(1, "A"), StatusCancelled, 2)
+	raced.History = append(withStatus(task(1, "A"), StatusDone, 1).History, raced.History...)
+
+	tests := []struct {
+		name   string
+		base   *TaskList
+		ours   *TaskList
+		theirs *TaskList
+		want   []Task
+		// conflicts is the number of conflicts, dropped how many of them
+		// dropped their value.
+		conflicts, dropped int
+	}{
+		{
+			name:   "added on our side",
+			ours:   list(task(1, "A"), task(2, "X")),
+			theirs: list(task(1, "A")),
+			want:   []Task{task(1, "A"), task(2, "X")},
+		},
+		{
+			name:   "added on their side",
+			ours:   list(task(1, "A")),
+			theirs: list(task(1, "A"), task(3, "Y")),
+			want:   []Task{task(1, "A"), task(3, "Y")},
+		},
+		{
+			name:   "added after the fork on ours, renamed on theirs",
+			base:   list(task(1, "A")),
+			ours:   list(task(1, "A"), task(2, "X")),
+			theirs: list(task(1, "A renamed")),
+			want:   []Task{task(1, "A renamed"), task(2, "X")},
+		},
+		{
+			name:   "done and renamed on theirs",
+			base:   list(task(1, "A")),
+			ours:   savedAt(list(task(1, "A")), 3),
+			theirs: list(withStatus(task(1, "A renamed"), StatusDone, 1)),
+			want:   []Task{withStatus(task(1, "A renamed"), StatusDone, 1)},
+		},
+		{
+			name:   "missing on one side without a base is kept",
+			ours:   list(task(1, "A")),
+			theirs: list(task(1, "A"), task(2, "B")),
+			want:   []Task{task(1, "A"), task(2, "B")},
+		},
+		{
+			name:   "removed on their side with a base",
+			base:   list(task(1, "A"), task(2, "B")),
+			ours:   list(task(1, "A"), task(2, "B")),
+			theirs: list(task(1, "A")),
+			want:   []Task{task(1, "A")},
+		},
+		{
+			name:   "removed on our side with a base",
+			base:   list(task(1, "A"), task(2, "B")),
+			ours:   list(task(1, "A")),
+			theirs: list(task(1, "A"), task(2, "B")),
+			want:   []Task{task(1, "A")},
+		},
+		{
+			name:   "removed on one side, changed on the other",
+			base:   list(task(1, "A"), task(2, "B")),
+			ours:   list(task(1, "A")),
+			theirs: list(task(1, "A"), withComment(task(2, "B"), "note")),
+			want:   []Task{task(1, "A"), withComment(task(2, "B"), "note")},
+		},
+		{
+			name:   "concurrent edits of different fields",
+			base:   list(task(1, "A")),
+			ours:   list(task(1, "A renamed")),
+			theirs: list(withComment(task(1, "A"), "note")),
+			want:   []Task{withComment(task(1, "A renamed"), "note")},
+		},
+		{
+			name:      "concurrent edits of the same field, newer ours wins",
+			base:      list(task(1, "A")),
+			ours:      savedAt(list(task(1, "A ours")), 2),
+			theirs:    savedAt(list(task(1, "A theirs")), 1),
+			want:      []Task{task(1, "A ours")},
+			conflicts: 1,
+			dropped:   1,
+		},
+		{
+			name:      "concurrent edits of the same field, newer theirs wins",
+			base:      list(task(1, "A")),
+			ours:      savedAt(list(task(1, "A ours")), 1),
+			theirs:    savedAt(list(task(1, "A theirs")), 2),
+			want:      []Task{task(1, "A theirs")},
+			conflicts: 1,
+		},
+		{
+			name:      "concurrent status changes, the later change wins",
+			base:      list(task(1, "A")),
+			ours:      savedAt(list(withStatus(task(1, "A"), StatusDone, 1)), 3),
+			theirs:    list(withStatus(task(1, "A"), StatusCancelled, 2)),
+			want:      []Task{raced},
+			conflicts: 1,
+		},
+		{
+			name:      "without a base every difference is a conflict",
+			ours:      list(task(1, "A")),
+			theirs:    savedAt(list(task(1, "A renamed")), 1),
+			want:      []Task{task(1, "A renamed")},
+			conflicts: 1,
+		},
+		{
+			name:   "sessions are unioned",
+			ours:   list(withSessions(task(1, "A"), 0, 2)),
+			theirs: list(withSessions(task(1, "A"), 0, 1)),
+			want:   []Task{withSessions(task(1, "A"), 0, 1, 2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, conflicts, err := mergeTaskLists(tt.base, tt.ours, tt.theirs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dropped := 0
+			for _, conflict := range conflicts {
+				if conflict.theirsDropped {
+					dropped++
+				}
+			}
+			if len(conflicts) != tt.conflicts || dropped != tt.dropped {
+				t.Errorf("got conflicts %v, want %d with %d of theirs dropped", conflicts, tt.conflicts, tt.dropped)
+			}
+			if len(result.Items) != len(tt.want) {
+				t.Fatalf("got %d tasks %v, want %d", len(result.Items), titles(result.Items), len(tt.want))
+			}
+			for i := range tt.want {
+				if !sameTask(&result.Items[i], &tt.want[i]) {
+					t.Errorf("task %d: got %+v, want %+v", i, result.Items[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func titles(tasks []Task) []string {
+	var names []string
+	for _, task := range tasks {
+		names = append(names, task.Title)
+	}
+	return names
+}
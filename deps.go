@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Dependencies link tasks in the same list by ID: a task with DependsOn is
+// blocked until all of those tasks are done. Tasks that no longer exist in
+// the list (removed or moved away) or were cancelled don't block anything.
+
+// blockers returns the open tasks task is waiting for.
+func blockers(taskList *TaskList, task *Task) []*Task {
+	var open []*Task
+	for _, id := range task.DependsOn {
+		index, err := findTaskIndex(taskList, id)
+		if err != nil {
+			continue
+		}
+		if dep := &taskList.Items[index-1]; !dep.IsClosed() {
+			open = append(open, dep)
+		}
+	}
+	return open
+}
+
+// isBlocked reports whether task waits for another task that isn't done.
+func isBlocked(taskList *TaskList, task *Task) bool {
+	return !task.IsClosed() && len(blockers(taskList, task)) > 0
+}
+
+func formatBlockers(blocking []*Task) string {
+	ids := make([]string, len(blocking))
+	for i, task := range blocking {
+		ids[i] = task.ShortID()
+	}
+	return strings.Join(ids, ", ")
+}
+
+// dependsOn reports whether the task with id depends on target, directly or
+// through other tasks.
+func dependsOn(taskList *TaskList, id, target int64, seen map[int64]bool) bool {
+	if id == target {
+		return true
+	}
+	if seen[id] {
+		return false
+	}
+	seen[id] = true
+
+	index, err := findTaskIndex(taskList, id)
+	if err != nil {
+		return false
+	}
+	for _, dep := range taskList.Items[index-1].DependsOn {
+		if dependsOn(taskList, dep, target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// addDependency makes the task at index wait for the task at depIndex. It
+// refuses links that would make a cycle.
+func addDependency(taskList *TaskList, index, depIndex int) error {
+	if index < 1 || index > len(taskList.Items) || depIndex < 1 || depIndex > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	dep := &taskList.Items[depIndex-1]
+	if task.ID == dep.ID {
+		return fmt.Errorf("a task cannot depend on itself")
+	}
+	if slices.Contains(task.DependsOn, dep.ID) {
+		return fmt.Errorf("'%s' already depends on '%s'", task.Title, dep.Title)
+	}
+	if dependsOn(taskList, dep.ID, task.ID, make(map[int64]bool)) {
+		return fmt.Errorf("'%s' already depends on '%s', that would be a cycle", dep.Title, task.Title)
+	}
+
+	task.DependsOn = append(task.DependsOn, dep.ID)
+	fmt.Printf("[+] %s now depends on %s\n", task.Title, dep.Title)
+	return nil
+}
+
+func removeDependency(taskList *TaskList, index, depIndex int) error {
+	if index < 1 || index > len(taskList.Items) || depIndex < 1 || depIndex > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	dep := &taskList.Items[depIndex-1]
+	if !slices.Contains(task.DependsOn, dep.ID) {
+		return fmt.Errorf("'%s' doesn't depend on '%s'", task.Title, dep.Title)
+	}
+
+	task.DependsOn = slices.DeleteFunc(task.DependsOn, func(id int64) bool { return id == dep.ID })
+	if len(task.DependsOn) == 0 {
+		task.DependsOn = nil
+	}
+	fmt.Printf("[-] %s no longer depends on %s\n", task.Title, dep.Title)
+	return nil
+}
+
+// dropDependency removes links to a task that is removed from the list.
+func dropDependency(taskList *TaskList, id int64) {
+	for i := range taskList.Items {
+		task := &taskList.Items[i]
+		task.DependsOn = slices.DeleteFunc(task.DependsOn, func(dep int64) bool { return dep == id })
+		if len(task.DependsOn) == 0 {
+			task.DependsOn = nil
+		}
+	}
+}
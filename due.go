@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// parseDue understands "today", "tomorrow", weekdays ("fri"), dates
+// ("2026-11-03") and offsets ("+3d", "+2w", "+4h"), each optionally followed
+// by a time ("fri 17:00"). A time alone means today. Due dates without a time
+// are stored at midnight and count as due the whole day.
+func parseDue(s string, now time.Time) (time.Time, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	invalid := fmt.Errorf("cannot parse due date '%s', use e.g. tomorrow, fri 17:00, 2026-11-03 or +3d", s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return time.Time{}, invalid
+	}
+
+	today := startOfDay(now)
+	day := fields[0]
+	clock := ""
+	if len(fields) == 2 {
+		clock = fields[1]
+	} else if strings.Contains(day, ":") {
+		day, clock = "today", day
+	}
+
+	hour, minute := 0, 0
+	if clock != "" {
+		t, err := time.Parse("15:04", clock)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		hour, minute = t.Hour(), t.Minute()
+	}
+	at := func(date time.Time) time.Time {
+		return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, now.Location())
+	}
+
+	if weekday, ok := weekdays[day]; ok {
+		days := (int(weekday) - int(now.Weekday()) + 7) % 7
+		due := at(today.AddDate(0, 0, days))
+		if days == 0 && (clock == "" || !due.After(now)) {
+			due = at(today.AddDate(0, 0, 7))
+		}
+		return due, nil
+	}
+
+	switch day {
+	case "today", "tod":
+		return at(today), nil
+	case "tomorrow", "tom":
+		return at(today.AddDate(0, 0, 1)), nil
+	}
+
+	if strings.HasPrefix(day, "+") && len(day) > 2 {
+		n, err := strconv.Atoi(day[1 : len(day)-1])
+		if err != nil || n < 0 || clock != "" && day[len(day)-1] == 'h' {
+			return time.Time{}, invalid
+		}
+		switch day[len(day)-1] {
+		case 'd':
+			return at(today.AddDate(0, 0, n)), nil
+		case 'w':
+			return at(today.AddDate(0, 0, 7*n)), nil
+		case 'h':
+			return now.Add(time.Duration(n) * time.Hour).Truncate(time.Minute), nil
+		}
+		return time.Time{}, invalid
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", day, now.Location())
+	if err != nil {
+		return time.Time{}, invalid
+	}
+	return at(date), nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// dueAllDay reports whether due was given without a time of day.
+func dueAllDay(due time.Time) bool {
+	return due.Equal(startOfDay(due))
+}
+
+// dueDeadline is the moment a task becomes overdue.
+func dueDeadline(due time.Time) time.Time {
+	if dueAllDay(due) {
+		return due.AddDate(0, 0, 1)
+	}
+	return due
+}
+
+// IsOverdue reports whether an open task is past its due date.
+func (t *Task) IsOverdue(now time.Time) bool {
+	return t.DueAt != nil && !t.IsClosed() && !now.Before(dueDeadline(t.DueAt.In(now.Location())))
+}
+
+// IsDueToday reports whether an open task is due later today.
+func (t *Task) IsDueToday(now time.Time) bool {
+	if t.DueAt == nil || t.IsClosed() || t.IsOverdue(now) {
+		return false
+	}
+	return startOfDay(t.DueAt.In(now.Location())).Equal(startOfDay(now))
+}
+
+// formatDue shows due relative to now: "today 17:00", "tomorrow", "Fri",
+// or the date if it's further away.
+func formatDue(due time.Time, now time.Time) string {
+	due = due.In(now.Location())
+	var day string
+	switch days := int(math.Round(startOfDay(due).Sub(startOfDay(now)).Hours() / 24)); {
+	case days == 0:
+		day = "today"
+	case days == 1:
+		day = "tomorrow"
+	case days == -1:
+		day = "yesterday"
+	case days > 1 && days < 7:
+		day = due.Format("Mon")
+	default:
+		day = due.Format("2006-01-02")
+	}
+
+	if dueAllDay(due) {
+		return day
+	}
+	return day + " " + due.Format("15:04")
+}
+
+func setTaskDue(taskList *TaskList, index int, due *time.Time) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	task.DueAt = due
+	if due == nil {
+		fmt.Printf("[~] Due date cleared: %s\n", task.Title)
+	} else {
+		fmt.Printf("[~] Due %s: %s\n", formatDue(*due, time.Now()), task.Title)
+	}
+	return nil
+}
+
+// parseDueArg parses the due date given to the due command, '-' clears it.
+func parseDueArg(s string) (*time.Time, error) {
+	if strings.TrimSpace(s) == "-" {
+		return nil, nil
+	}
+	due, err := parseDue(s, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &due, nil
+}
+
+type dueTask struct {
+	listName string
+	task     Task
+}
+
+// showDueTasks prints the open tasks with a due date in all lists, or only
+// in listName if it's given, soonest first.
+func showDueTasks(config *Config, listName string) error {
+	store, err := openStore(config)
+	if err != nil {
+		return err
+	}
+	listNames, err := store.Lists()
+	if err != nil {
+		return err
+	}
+	if listName != "" {
+		name, err := findList(listNames, listName)
+		if err != nil {
+			return err
+		}
+		listNames = []string{name}
+	}
+
+	var tasks []dueTask
+	for _, name := range listNames {
+		taskList, err := store.Load(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!] Skipping %s: %v\n", name, err)
+			continue
+		}
+		for _, task := range taskList.Items {
+			if task.DueAt != nil && !task.IsClosed() {
+				tasks = append(tasks, dueTask{listName: name, task: task})
+			}
+		}
+	}
+	if len(tasks) == 0 {
+		fmt.Println("[i] No open tasks with a due date")
+		return nil
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return dueDeadline(*tasks[i].task.DueAt).Before(dueDeadline(*tasks[j].task.DueAt))
+	})
+
+	now := time.Now()
+	dueWidth, listWidth := 0, 0
+	for _, t := range tasks {
+		dueWidth = max(dueWidth, len(formatDue(*t.task.DueAt, now)))
+		listWidth = max(listWidth, len(t.listName))
+	}
+
+	section := ""
+	for _, t := range tasks {
+		var heading string
+		switch {
+		case t.task.IsOverdue(now):
+			heading = "OVERDUE"
+		case t.task.IsDueToday(now):
+			heading = "DUE TODAY"
+		default:
+			heading = "UPCOMING"
+		}
+		if heading != section {
+			fmt.Printf("\n  %s\n  %s\n", heading, strings.Repeat("-", len(heading)))
+			section = heading
+		}
+
+		title := t.task.Title
+		if len(t.task.Tags) > 0 {
+			title += " " + formatTags(t.task.Tags)
+		}
+		fmt.Printf("  %-*s  %-*s  %s %s\n", dueWidth, formatDue(*t.task.DueAt, now), listWidth, t.listName, t.task.ShortID(), title)
+	}
+	fmt.Println()
+	return nil
+}
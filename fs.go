@@ -1,11 +1,125 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
+const backupSuffix = ".bak"
+
+// fileSnapshot identifies the on-disk version a TaskList was loaded from.
+type fileSnapshot struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+func snapshotFile(path string, data []byte) (*fileSnapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSnapshot{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		hash:    sha256.Sum256(data),
+	}, nil
+}
+
+// changed reports whether path no longer holds the snapshotted contents.
+// Matching mtime and size are trusted; otherwise the contents are hashed, so a
+// sync client merely touching the file isn't reported as a change.
+func (s *fileSnapshot) changed(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.Size() != s.size {
+		return true, nil
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	hash := sha256.Sum256(data)
+	return !bytes.Equal(hash[:], s.hash[:]), nil
+}
+
+// writeFileAtomic writes data to a temp file next to path, syncs it and
+// renames it over path, so readers (and sync clients) only ever see the old
+// or the new contents. If keepBackup is set, the previous contents are kept
+// in path+".bak", unless they no longer parse as a task list: a corrupt file
+// must not replace the last good backup it would be recovered from.
+func writeFileAtomic(path string, data []byte, perm os.FileMode, keepBackup bool) error {
+	dir := filepath.Dir(path)
+
+	if keepBackup {
+		if prev, err := os.ReadFile(path); err == nil && len(prev) > 0 && backupWorthy(prev) {
+			if err := writeFileAtomic(path+backupSuffix, prev, perm, false); err != nil {
+				return fmt.Errorf("cannot write backup: %v", err)
+			}
+		}
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// backupWorthy reports whether prev can be recovered from, i.e. still parses
+// as a task list.
+func backupWorthy(prev []byte) bool {
+	_, err := decodeTaskList(prev)
+	return err == nil
+}
+
+// syncDir flushes the directory entry after a rename. Not every platform
+// supports this (Windows doesn't), so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
 func showDirContents(folder string) {
 	entries, err := os.ReadDir(folder)
 	if err != nil {
@@ -14,11 +128,14 @@ func showDirContents(folder string) {
 
 	fmt.Printf("\nFiles in %s:\n", folder)
 	var taskFiles []string
+	var conflictFiles []string
 	var otherFiles []string
 
 	for _, entry := range entries {
 		if !entry.IsDir() {
-			if strings.HasSuffix(entry.Name(), ".json") {
+			if _, isConflict := conflictTarget(entry.Name()); isConflict {
+				conflictFiles = append(conflictFiles, entry.Name())
+			} else if strings.HasSuffix(entry.Name(), ".json") {
 				taskFiles = append(taskFiles, entry.Name())
 			} else {
 				otherFiles = append(otherFiles, entry.Name())
@@ -33,6 +150,13 @@ func showDirContents(folder string) {
 		}
 	}
 
+	if len(conflictFiles) > 0 {
+		fmt.Println("  Sync conflicts (run 'tgo merge-conflicts'):")
+		for _, file := range conflictFiles {
+			fmt.Printf("    - %s\n", file)
+		}
+	}
+
 	if len(otherFiles) > 0 {
 		fmt.Println("  Other files:")
 		for _, file := range otherFiles {
@@ -40,7 +164,7 @@ func showDirContents(folder string) {
 		}
 	}
 
-	if len(taskFiles) == 0 && len(otherFiles) == 0 {
+	if len(taskFiles) == 0 && len(conflictFiles) == 0 && len(otherFiles) == 0 {
 		fmt.Println("  (no files found)")
 	}
 	fmt.Println()
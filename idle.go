@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Interactive mode counts the time between two inputs as idle when it is
+// longer than the idle-after setting, and asks what to do with it if timers
+// ran in the meantime.
+
+const defaultIdleAfter = 15 * time.Minute
+
+// idleAfter returns how long interactive mode may go without input before it
+// asks about idle time, 0 if it never asks.
+func (c *Config) idleAfter() time.Duration {
+	return settingDuration(c.IdleAfter, defaultIdleAfter, true)
+}
+
+// idleTimers returns the tasks and subtasks whose timer was already running
+// when the user went idle.
+func idleTimers(taskList *TaskList, idleSince time.Time) []*Task {
+	var running []*Task
+	check := func(task *Task) {
+		if task.ActiveStartTime != nil && task.ActiveStartTime.Before(idleSince) {
+			running = append(running, task)
+		}
+	}
+	for i := range taskList.Items {
+		check(&taskList.Items[i])
+		for j := range taskList.Items[i].Children {
+			check(&taskList.Items[i].Children[j])
+		}
+	}
+	return running
+}
+
+const (
+	idleKeep     = "keep"
+	idleSubtract = "subtract"
+	idleSplit    = "split"
+)
+
+// resolveIdle deals with the idle time from idleSince to now on a running
+// timer. Subtracting it ends the session when the user went idle, splitting
+// records the idle time as a session of its own. Either way the timer goes on
+// running from now.
+func resolveIdle(task *Task, action string, idleSince, now time.Time) error {
+	if task.ActiveStartTime == nil || !task.ActiveStartTime.Before(idleSince) {
+		return fmt.Errorf("'%s' was restarted in the meantime", task.Title)
+	}
+
+	switch action {
+	case idleKeep:
+		task.touch(now)
+		return nil
+	case idleSubtract:
+		addWorkedSession(task, *task.ActiveStartTime, idleSince)
+		fmt.Printf("[-] Subtracted %s idle time: %s\n", formatDuration(now.Sub(idleSince).Nanoseconds()), task.Title)
+	case idleSplit:
+		addWorkedSession(task, *task.ActiveStartTime, idleSince)
+		addWorkedSession(task, idleSince, now)
+		fmt.Printf("[~] Split %s idle time into its own session: %s\n", formatDuration(now.Sub(idleSince).Nanoseconds()), task.Title)
+	default:
+		return fmt.Errorf("unknown action '%s'", action)
+	}
+	task.ActiveStartTime = &now
+	return nil
+}
+
+// addWorkedSession records the session from start to end, unless it is too
+// short to count, like a focus block stopped right away.
+func addWorkedSession(task *Task, start, end time.Time) {
+	if end.Sub(start) < time.Second {
+		return
+	}
+	task.addSession(newSession(start, end))
+}
+
+// askIdleTime asks what to do with the time since the last input when it
+// was long enough to count as idle and a timer was running.
+func askIdleTime(s *session, idleSince time.Time) {
+	idleAfter := s.config.idleAfter()
+	now := time.Now()
+	if idleAfter == 0 || now.Sub(idleSince) < idleAfter {
+		return
+	}
+
+	for _, task := range idleTimers(s.taskList, idleSince) {
+		fmt.Printf("\n[i] You were away for %s (since %s) while '%s' was running\n",
+			formatDuration(now.Sub(idleSince).Nanoseconds()), idleSince.Format("15:04"), task.Title)
+		taskID := task.ID
+
+		var action string
+		for action == "" {
+			fmt.Print("    s subtract the idle time | p split it into its own session | k keep it: ")
+			line, ok := readLine()
+			if !ok {
+				return
+			}
+			switch strings.TrimSpace(strings.ToLower(line)) {
+			case "k", "keep":
+				action = idleKeep
+			case "s", "subtract":
+				action = idleSubtract
+			case "p", "split":
+				action = idleSplit
+			}
+		}
+
+		err := s.update(func(taskList *TaskList) error {
+			task, err := findAnyTask(taskList, taskID)
+			if err != nil {
+				return err
+			}
+			return resolveIdle(task, action, idleSince, now)
+		})
+		if err != nil {
+			fmt.Printf("[!] %v\n", err)
+		}
+	}
+}
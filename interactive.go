@@ -0,0 +1,650 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func runInteractiveMode(config *Config, requestedList string) {
+	if config.TaskDir == "" {
+		fmt.Println("[!] No task directory configured")
+		fmt.Println("Use: tgo set-dir <path>")
+		return
+	}
+
+	store, err := openStore(config)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	listNames, err := store.Lists()
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+	if len(listNames) == 0 {
+		fmt.Printf("[i] No task lists found in: %s\n\n", config.TaskDir)
+		fmt.Println("Let's create your first task list!")
+		handleCreateFirstList(config, store)
+		return
+	}
+
+	var listName string
+	if requestedList != "" {
+		listName, err = findList(listNames, requestedList)
+	} else {
+		listName, err = selectTaskList(store, listNames)
+	}
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+	rememberList(config, listName)
+
+	taskList, err := store.Load(listName)
+	if err != nil {
+		fmt.Printf("[!] Error loading tasks: %v\n", err)
+		return
+	}
+
+	runInteractiveLoop(&session{config: config, store: store, listName: listName, taskList: taskList})
+}
+
+// session is the task list open in interactive mode.
+type session struct {
+	config   *Config
+	store    Store
+	listName string
+	taskList *TaskList
+	filter   *taskFilter
+	// view replaces the task list until the next input, e.g. a task's history.
+	view []string
+}
+
+// update applies op to the open list and saves it, see updateTasks.
+func (s *session) update(op func(*TaskList) error) error {
+	return updateTasks(s.store, s.listName, s.taskList, op)
+}
+
+// updateTimer is update for starting or stopping a timer, see updateTimer.
+func (s *session) updateTimer(op func(*TaskList) error) error {
+	return updateTimer(s.config, s.store, s.listName, s.taskList, op)
+}
+
+// updateTask runs op on the task ref points at in the list as shown. The
+// task is looked up by ID inside the update, so it's still the right one if
+// the list had to be reloaded.
+func (s *session) updateTask(ref string, op func(taskList *TaskList, index int) error) error {
+	taskID, err := resolveTaskID(s.taskList, ref)
+	if err != nil {
+		return err
+	}
+
+	return s.update(func(taskList *TaskList) error {
+		index, err := findTaskIndex(taskList, taskID)
+		if err != nil {
+			return err
+		}
+		return op(taskList, index)
+	})
+}
+
+// updateSubtask is updateTask for a <task>.<n> subtask.
+func (s *session) updateSubtask(ref string, op func(taskList *TaskList, index, child int) error) error {
+	taskID, childID, err := resolveSubtaskID(s.taskList, ref)
+	if err != nil {
+		return err
+	}
+
+	return s.update(func(taskList *TaskList) error {
+		index, child, err := findSubtaskIndex(taskList, taskID, childID)
+		if err != nil {
+			return err
+		}
+		return op(taskList, index, child)
+	})
+}
+
+// updateAnyTask is updateTask for a task or a <task>.<n> subtask.
+func (s *session) updateAnyTask(ref string, op func(taskList *TaskList, task *Task) error) error {
+	task, err := resolveAnyTask(s.taskList, ref)
+	if err != nil {
+		return err
+	}
+	taskID := task.ID
+
+	return s.update(func(taskList *TaskList) error {
+		task, err := findAnyTask(taskList, taskID)
+		if err != nil {
+			return err
+		}
+		return op(taskList, task)
+	})
+}
+
+// updateTrackedTask is updateAnyTask for an operation on tracked time, which
+// gets the other lists of the store as well, see checkSession.
+func (s *session) updateTrackedTask(ref string, op func(taskList *TaskList, others []*TaskList, task *Task) error) error {
+	others, err := loadOtherLists(s.store, s.listName)
+	if err != nil {
+		return err
+	}
+	return s.updateAnyTask(ref, func(taskList *TaskList, task *Task) error {
+		return op(taskList, others, task)
+	})
+}
+
+func runInteractiveLoop(s *session) {
+	askStaleTimers(s)
+	spinnerStart := time.Now()
+
+	render := func() {
+		if s.view != nil {
+			drawFullScreen(s.view, " Enter to return to the list ")
+			s.view = nil
+			fmt.Print("\n> ")
+			return
+		}
+		frame := int(time.Since(spinnerStart) / (150 * time.Millisecond))
+		displayTaskListWithSpinner(s.taskList, s.listName, frame, s.filter)
+		fmt.Print("\n> ")
+	}
+
+	lastInput := time.Now()
+	for {
+		render()
+		line, ok := readLine()
+		if !ok {
+			return
+		}
+		askIdleTime(s, lastInput)
+		lastInput = time.Now()
+
+		input := strings.TrimSpace(line)
+		if input == "" {
+			continue
+		}
+
+		if handleInteractiveCommand(input, s) {
+			return
+		}
+		// Commands like pomo can run for a while without input.
+		lastInput = time.Now()
+
+		spinnerStart = time.Now()
+	}
+}
+
+func handleInteractiveCommand(input string, s *session) bool {
+	switch {
+	case input == "q" || input == "quit" || input == "exit":
+		return true
+	case input == "r" || input == "return":
+		runInteractiveMode(s.config, "")
+		return true
+	case strings.HasPrefix(input, "add "):
+		handleAddTask(strings.TrimSpace(input[4:]), s)
+	case strings.HasPrefix(input, "a "):
+		handleAddTask(strings.TrimSpace(input[2:]), s)
+	case strings.HasPrefix(input, "remove "):
+		handleRemoveTask(strings.TrimSpace(input[7:]), s)
+	case strings.HasPrefix(input, "r "):
+		handleRemoveTask(strings.TrimSpace(input[2:]), s)
+	case strings.HasPrefix(input, "done "):
+		handleDoneTask(strings.TrimSpace(input[5:]), s)
+	case strings.HasPrefix(input, "d "):
+		handleDoneTask(strings.TrimSpace(input[2:]), s)
+	case strings.HasPrefix(input, "reopen "):
+		handleReopenTask(strings.TrimSpace(input[7:]), s)
+	case strings.HasPrefix(input, "history "):
+		handleHistoryTask(strings.TrimSpace(input[8:]), s)
+	case strings.HasPrefix(input, "pomo "):
+		handlePomodoroTask(strings.TrimSpace(input[5:]), s)
+	case strings.HasPrefix(input, "log "):
+		handleLogTask(input[4:], s)
+	case strings.HasPrefix(input, "sessions "):
+		handleSessionsTask(input[9:], s)
+	case strings.HasPrefix(input, "cancel "):
+		handleCancelTask(strings.TrimSpace(input[7:]), s)
+	case strings.HasPrefix(input, "wait "):
+		handleWaitTask(input[5:], s)
+	case strings.HasPrefix(input, "edit "):
+		handleEditTask(input[5:], s)
+	case strings.HasPrefix(input, "e "):
+		handleEditTask(input[2:], s)
+	case strings.HasPrefix(input, "due "):
+		handleDueTask(input[4:], s)
+	case strings.HasPrefix(input, "sub "):
+		handleAddSubtask(input[4:], s)
+	case strings.HasPrefix(input, "check "):
+		handleSubtask(input[6:], s, toggleSubtaskDone)
+	case strings.HasPrefix(input, "unsub "):
+		handleSubtask(input[6:], s, removeSubtask)
+	case strings.HasPrefix(input, "repeat "):
+		handleRepeatTask(input[7:], s)
+	case strings.HasPrefix(input, "dep "):
+		handleDependencyTask(input[4:], s, addDependency)
+	case strings.HasPrefix(input, "undep "):
+		handleDependencyTask(input[6:], s, removeDependency)
+	case strings.HasPrefix(input, "move "):
+		handleMoveTask(input[5:], s)
+	case strings.HasPrefix(input, "mv "):
+		handleMoveTaskToList(input[3:], s)
+	case strings.HasPrefix(input, "prio "):
+		handlePriorityTask(input[5:], s)
+	case strings.HasPrefix(input, "sort "):
+		handleSortList(input[5:], s)
+	case strings.HasPrefix(input, "tag "):
+		handleTagTask(input[4:], s, tagTask)
+	case strings.HasPrefix(input, "untag "):
+		handleTagTask(input[6:], s, untagTask)
+	case input == "filter" || strings.HasPrefix(input, "filter "):
+		handleFilter(input[6:], s)
+	case strings.HasPrefix(input, "note "):
+		handleNoteTask(input[5:], s)
+	case strings.HasPrefix(input, "n "):
+		handleNoteTask(input[2:], s)
+	default:
+		if _, err := resolveTaskRef(s.taskList, input); err == nil {
+			handleToggleTimer(input, s)
+		} else if _, _, err := resolveSubtaskRef(s.taskList, input); err == nil {
+			handleToggleSubtaskTimer(input, s)
+		} else {
+			fmt.Println("[!] Invalid command. Type a number or ID, 'add / a <task>', 'remove / r <number>', 'done / d <number>', 'reopen <number>', 'history <number>', 'pomo <number>', 'log <number> <time>', 'sessions <number> [<n> set|split|rm]', 'cancel <number>', 'wait <number> [note] [until <when>]', 'edit / e <number> <title>', 'note / n <number> [text]', 'due <number> <when>', 'sub <number> <title>', 'check / unsub <number>.<n>', 'repeat <number> <rule>', 'dep / undep <number> <other>', 'move <number> <pos>', 'mv <number> <list>', 'prio <number> <H|M|L>', 'sort <mode>', 'tag / untag <number> +tag', 'filter [+tag|status]', 'r' to return, or 'q' to quit")
+		}
+	}
+	return false
+}
+
+func handleAddTask(taskTitle string, s *session) {
+	if taskTitle == "" {
+		fmt.Println("[!] Task title cannot be empty")
+		return
+	}
+
+	err := s.update(func(taskList *TaskList) error {
+		return addTask(taskList, taskTitle)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleRemoveTask(taskRef string, s *session) {
+	if err := s.updateTask(taskRef, removeTask); err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleDoneTask(taskRef string, s *session) {
+	if err := s.updateTask(taskRef, markTaskComplete); err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleToggleTimer(taskRef string, s *session) {
+	taskID, err := resolveTaskID(s.taskList, taskRef)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	err = s.updateTimer(func(taskList *TaskList) error {
+		index, err := findTaskIndex(taskList, taskID)
+		if err != nil {
+			return err
+		}
+		return toggleTaskTimer(taskList, index)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleToggleSubtaskTimer(ref string, s *session) {
+	taskID, childID, err := resolveSubtaskID(s.taskList, ref)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	err = s.updateTimer(func(taskList *TaskList) error {
+		index, child, err := findSubtaskIndex(taskList, taskID, childID)
+		if err != nil {
+			return err
+		}
+		return toggleSubtaskTimer(taskList, index, child)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleReopenTask(taskRef string, s *session) {
+	if err := s.updateTask(taskRef, reopenTask); err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleHistoryTask(taskRef string, s *session) {
+	task, err := resolveAnyTask(s.taskList, taskRef)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+	s.view = getHistoryLines(task)
+}
+
+func handlePomodoroTask(taskRef string, s *session) {
+	start := time.Now()
+	pomo, err := newPomodoro(s.config, s.store, s.listName, s.taskList, taskRef, func(footer string) {
+		frame := int(time.Since(start) / (150 * time.Millisecond))
+		drawFullScreen(getTaskListLines(s.taskList, s.listName, frame, s.filter), footer)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+	if err := pomo.run(); err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleLogTask(args string, s *session) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		fmt.Println("[!] Task number or ID required")
+		return
+	}
+	start, end, err := parseLogArgs(fields[1:], time.Now())
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+	err = s.updateTrackedTask(fields[0], func(taskList *TaskList, others []*TaskList, task *Task) error {
+		return logTime(taskList, others, task, start, end)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleSessionsTask(args string, s *session) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		fmt.Println("[!] Task number or ID required")
+		return
+	}
+	if len(fields) == 1 {
+		task, err := resolveAnyTask(s.taskList, fields[0])
+		if err != nil {
+			fmt.Printf("[!] %v\n", err)
+			return
+		}
+		s.view = getSessionLines(task, time.Now())
+		return
+	}
+
+	err := s.updateTrackedTask(fields[0], func(taskList *TaskList, others []*TaskList, task *Task) error {
+		return changeSession(taskList, others, task, fields[1:])
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleCancelTask(taskRef string, s *session) {
+	if err := s.updateTask(taskRef, cancelTask); err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleWaitTask(args string, s *session) {
+	taskRef, rest := splitFirstWord(args)
+	if rest == "-" {
+		if err := s.updateTask(taskRef, stopWaiting); err != nil {
+			fmt.Printf("[!] %v\n", err)
+		}
+		return
+	}
+
+	note, followUp, err := parseWaitArgs(rest)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+	err = s.updateTask(taskRef, func(taskList *TaskList, index int) error {
+		return waitTask(taskList, index, note, followUp)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleEditTask(args string, s *session) {
+	taskRef, title := splitFirstWord(args)
+	err := s.updateTask(taskRef, func(taskList *TaskList, index int) error {
+		return editTaskTitle(taskList, index, title)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleDueTask(args string, s *session) {
+	taskRef, when := splitFirstWord(args)
+	if when == "" {
+		fmt.Println("[!] Due date required, e.g. tomorrow, fri 17:00, 2026-11-03 or +3d ('-' clears)")
+		return
+	}
+	due, err := parseDueArg(when)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	err = s.updateTask(taskRef, func(taskList *TaskList, index int) error {
+		return setTaskDue(taskList, index, due)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleAddSubtask(args string, s *session) {
+	taskRef, title := splitFirstWord(args)
+	err := s.updateTask(taskRef, func(taskList *TaskList, index int) error {
+		return addSubtask(taskList, index, title)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleSubtask(ref string, s *session, op func(taskList *TaskList, index, child int) error) {
+	if err := s.updateSubtask(strings.TrimSpace(ref), op); err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleRepeatTask(args string, s *session) {
+	taskRef, value := splitFirstWord(args)
+	rule, err := parseRecurrenceArg(value)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	err = s.updateTask(taskRef, func(taskList *TaskList, index int) error {
+		return setTaskRecurrence(taskList, index, rule)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleDependencyTask(args string, s *session, op func(taskList *TaskList, index, depIndex int) error) {
+	taskRef, depRef := splitFirstWord(args)
+	depID, err := resolveTaskID(s.taskList, depRef)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	err = s.updateTask(taskRef, func(taskList *TaskList, index int) error {
+		depIndex, err := findTaskIndex(taskList, depID)
+		if err != nil {
+			return err
+		}
+		return op(taskList, index, depIndex)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleMoveTask(args string, s *session) {
+	taskRef, value := splitFirstWord(args)
+	pos, err := strconv.Atoi(value)
+	if err != nil {
+		fmt.Printf("[!] Invalid position '%s'\n", value)
+		return
+	}
+
+	err = s.updateTask(taskRef, func(taskList *TaskList, index int) error {
+		return moveTask(taskList, index, pos)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleMoveTaskToList(args string, s *session) {
+	taskRef, toList := splitFirstWord(args)
+	if toList == "" {
+		fmt.Println("[!] List name required")
+		return
+	}
+	taskID, err := resolveTaskID(s.taskList, taskRef)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	if err := transferTask(s.store, s.listName, s.taskList, taskID, toList); err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handlePriorityTask(args string, s *session) {
+	taskRef, value := splitFirstWord(args)
+	priority, err := parsePriority(value)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	err = s.updateTask(taskRef, func(taskList *TaskList, index int) error {
+		return setTaskPriority(taskList, index, priority)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleSortList(args string, s *session) {
+	mode, err := parseSortMode(args)
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	err = s.update(func(taskList *TaskList) error {
+		setSortMode(taskList, mode)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleTagTask(args string, s *session, op func(*TaskList, int, []string) error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		fmt.Println("[!] Task number or ID required")
+		return
+	}
+	tags, err := parseTagArgs(fields[1:])
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+
+	err = s.updateTask(fields[0], func(taskList *TaskList, index int) error {
+		return op(taskList, index, tags)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+// handleFilter only shows tasks with the given tags. Without tags it shows
+// all tasks again.
+func handleFilter(args string, s *session) {
+	filter, err := parseFilter(strings.Fields(args))
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+		return
+	}
+	s.filter = filter
+}
+
+// handleNoteTask sets the comment of a task. Without text the comment is
+// opened in $EDITOR, '-' clears it.
+func handleNoteTask(args string, s *session) {
+	taskRef, comment := splitFirstWord(args)
+	if comment == "" {
+		index, err := resolveTaskRef(s.taskList, taskRef)
+		if err != nil {
+			fmt.Printf("[!] %v\n", err)
+			return
+		}
+		if comment, err = editText(s.taskList.Items[index-1].Comment); err != nil {
+			fmt.Printf("[!] %v\n", err)
+			return
+		}
+	} else if comment == "-" {
+		comment = ""
+	}
+
+	err := s.updateTask(taskRef, func(taskList *TaskList, index int) error {
+		return setTaskComment(taskList, index, comment)
+	})
+	if err != nil {
+		fmt.Printf("[!] %v\n", err)
+	}
+}
+
+func handleCreateFirstList(config *Config, store Store) {
+	fmt.Print("Enter your first list name: ")
+	if line, ok := readLine(); ok {
+		listName := strings.TrimSpace(line)
+		if listName == "" {
+			fmt.Println("[!] List name cannot be empty")
+			return
+		}
+
+		if _, err := createList(store, listName); err != nil {
+			fmt.Printf("[!] %v\n", err)
+			return
+		}
+
+		fmt.Printf("[+] Created your first list: %s\n", listName)
+		fmt.Println("[>] Starting interactive mode...")
+		time.Sleep(time.Second)
+		runInteractiveMode(config, "")
+	}
+}
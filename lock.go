@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	lockSuffix     = ".lock"
+	lockTimeout    = 5 * time.Second
+	lockRetryDelay = 50 * time.Millisecond
+	// Locks are only held for a single read-modify-write, so anything older
+	// than this was left behind by a crashed process.
+	lockStaleAfter = 30 * time.Second
+)
+
+type lockInfo struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// lockFile takes an advisory lock on path by creating path+".lock". It waits
+// up to lockTimeout for another tgo process to release it and removes locks
+// whose owner is gone. The returned func releases the lock.
+func lockFile(path string) (func(), error) {
+	lockPath := path + lockSuffix
+	host, _ := os.Hostname()
+	info := lockInfo{PID: os.Getpid(), Host: host, CreatedAt: time.Now()}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, werr := f.Write(data)
+			cerr := f.Close()
+			if werr != nil || cerr != nil {
+				os.Remove(lockPath)
+				return nil, fmt.Errorf("cannot write lock file: %v", firstErr(werr, cerr))
+			}
+			return func() { releaseLock(lockPath, data) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("cannot create lock file: %v", err)
+		}
+
+		owner, stale := readLock(lockPath, host)
+		if stale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another tgo process (pid %d on %s), try again later",
+				filepath.Base(path), owner.PID, owner.Host)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+// readLock reports who holds lockPath and whether that lock can be broken.
+func readLock(lockPath string, host string) (lockInfo, bool) {
+	var owner lockInfo
+	data, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return owner, false
+	}
+
+	stat, statErr := os.Stat(lockPath)
+	if err != nil || json.Unmarshal(data, &owner) != nil {
+		// Unreadable or half-written lock: only break it once it's old,
+		// its owner may still be writing it.
+		return owner, statErr == nil && time.Since(stat.ModTime()) > lockStaleAfter
+	}
+
+	if owner.Host == host && !processAlive(owner.PID) {
+		return owner, true
+	}
+	return owner, time.Since(owner.CreatedAt) > lockStaleAfter
+}
+
+// releaseLock removes lockPath unless someone else has taken it over after
+// our lock was considered stale.
+func releaseLock(lockPath string, data []byte) {
+	current, err := os.ReadFile(lockPath)
+	if err != nil || string(current) != string(data) {
+		return
+	}
+	os.Remove(lockPath)
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
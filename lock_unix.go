@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import "syscall"
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -1,25 +1,58 @@
 package main
 
 import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/fnv"
 	"time"
 )
 
 type Task struct {
-	ID              int64      `json:"id"`
-	Title           string     `json:"title"`
-	Status          TaskStatus `json:"status"`
-	Comment         string     `json:"comment"`
-	Sessions        []Session  `json:"sessions"`
-	TotalDuration   int64      `json:"total_duration"`
-	ActiveStartTime *time.Time `json:"active_start_time,omitempty"`
-	CompletedAt     *time.Time `json:"completed_at,omitempty"`
-	CreatedAt       time.Time  `json:"created_at"`
+	ID              int64        `json:"id"`
+	Title           string       `json:"title"`
+	Status          TaskStatus   `json:"status"`
+	Comment         string       `json:"comment"`
+	Tags            []string     `json:"tags,omitempty"`
+	Priority        TaskPriority `json:"priority,omitempty"`
+	Sessions        []Session    `json:"sessions"`
+	TotalDuration   int64        `json:"total_duration"`
+	ActiveStartTime *time.Time   `json:"active_start_time,omitempty"`
+	CompletedAt     *time.Time   `json:"completed_at,omitempty"`
+	DueAt           *time.Time   `json:"due_at,omitempty"`
+	DependsOn       []int64      `json:"depends_on,omitempty"`
+	Recur           string       `json:"recur,omitempty"`
+	// NextInstance is the ID of the instance added when this instance of a
+	// recurring task was completed or cancelled.
+	NextInstance int64          `json:"next_instance,omitempty"`
+	WaitingOn    string         `json:"waiting_on,omitempty"`
+	FollowUpAt   *time.Time     `json:"follow_up_at,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Children     []Task         `json:"children,omitempty"`
+	History      []StatusChange `json:"history,omitempty"`
+	// LastActivity is the last timer or status action on the task or its
+	// subtasks, see touch.
+	LastActivity *time.Time `json:"last_activity,omitempty"`
+
+	extra map[string]json.RawMessage
 }
 
 type Session struct {
 	StartTime time.Time `json:"start_time"`
 	EndTime   time.Time `json:"end_time"`
 	Duration  int64     `json:"duration"`
+	// Pomodoro marks a focus block that ran its full length.
+	Pomodoro bool `json:"pomodoro,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+// StatusChange records one status transition of a task.
+type StatusChange struct {
+	At   time.Time  `json:"at"`
+	From TaskStatus `json:"from"`
+	To   TaskStatus `json:"to"`
+
+	extra map[string]json.RawMessage
 }
 
 type TaskStatus string
@@ -29,17 +62,84 @@ const (
 	StatusActive  TaskStatus = "active"
 	StatusPaused  TaskStatus = "paused"
 	StatusDone    TaskStatus = "done"
+	// A cancelled task won't be done. It is kept, unlike a removed one, so
+	// it still shows up in reports.
+	StatusCancelled TaskStatus = "cancelled"
+	// A waiting task is on hold until someone else delivers, see WaitingOn
+	// and FollowUpAt.
+	StatusWaiting TaskStatus = "waiting"
 )
 
 type TaskList struct {
-	Title     string    `json:"title"`
-	Items     []Task    `json:"items"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	SchemaVersion int       `json:"schema_version"`
+	Title         string    `json:"title"`
+	Items         []Task    `json:"items"`
+	Sort          string    `json:"sort,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// SyncBase is the hash of the last version written by another device
+	// this one was built on, see rememberLoad.
+	SyncBase string `json:"sync_base,omitempty"`
+
+	extra map[string]json.RawMessage
+	// The stored version this list was loaded from: source for the JSON
+	// store, revision for SQLite.
+	source   *fileSnapshot
+	revision int64
 }
 
 type Config struct {
-	TaskDir string `json:"task_folder"`
+	TaskDir       string `json:"task_folder"`
+	Store         string `json:"store,omitempty"`
+	DefaultList   string `json:"default_list,omitempty"`
+	LastList      string `json:"last_list,omitempty"`
+	StaleAfter    string `json:"stale_after,omitempty"`
+	IdleAfter     string `json:"idle_after,omitempty"`
+	SingleTimer   bool   `json:"single_timer,omitempty"`
+	PomoFocus     string `json:"pomo_focus,omitempty"`
+	PomoBreak     string `json:"pomo_break,omitempty"`
+	PomoLongBreak string `json:"pomo_long_break,omitempty"`
+}
+
+// ShortID is a short identifier for the task derived from its ID, so it is
+// the same on every device and doesn't change when other tasks are removed.
+func (t *Task) ShortID() string {
+	return shortTaskID(t.ID)
+}
+
+// shortTaskID formats a letter, a digit and two alphanumerics, e.g. "k3f9".
+// The digit keeps short IDs from ever looking like a word or a command.
+func shortTaskID(id int64) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	const digits = "0123456789"
+	const alphanumerics = letters + digits
+
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, id)
+	n := h.Sum64()
+
+	short := make([]byte, 4)
+	short[0] = letters[n%uint64(len(letters))]
+	n /= uint64(len(letters))
+	short[1] = digits[n%uint64(len(digits))]
+	n /= uint64(len(digits))
+	for i := 2; i < len(short); i++ {
+		short[i] = alphanumerics[n%uint64(len(alphanumerics))]
+		n /= uint64(len(alphanumerics))
+	}
+	return string(short)
+}
+
+func isShortID(s string) bool {
+	if len(s) != 4 || s[0] < 'a' || s[0] > 'z' || s[1] < '0' || s[1] > '9' {
+		return false
+	}
+	for _, c := range s[2:] {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
 }
 
 func (t *Task) IsActive() bool {
@@ -50,6 +150,69 @@ func (t *Task) IsDone() bool {
 	return t.Status == StatusDone
 }
 
+// TrackedDuration is the total time tracked on the task and its subtasks,
+// including running sessions, in nanoseconds.
+func (t *Task) TrackedDuration(now time.Time) int64 {
+	total := t.TotalDuration
+	if t.ActiveStartTime != nil {
+		total += now.Sub(*t.ActiveStartTime).Nanoseconds()
+	}
+	for i := range t.Children {
+		total += t.Children[i].TrackedDuration(now)
+	}
+	return total
+}
+
 func (t *Task) GetFormattedDuration() string {
-	return formatDuration(t.TotalDuration)
+	return formatDuration(t.TrackedDuration(time.Now()))
+}
+
+// The JSON methods below keep fields written by newer versions of tgo, so
+// saving a list never strips data this version doesn't understand.
+
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plain Task
+	if err := unmarshalKeepingUnknown(data, (*plain)(t), &t.extra); err != nil {
+		return err
+	}
+	// Lists written before sessions were kept may only have the total.
+	if len(t.Sessions) > 0 {
+		t.recomputeDuration()
+	}
+	return nil
+}
+
+func (t Task) MarshalJSON() ([]byte, error) {
+	type plain Task
+	return marshalWithUnknown(plain(t), t.extra)
+}
+
+func (s *Session) UnmarshalJSON(data []byte) error {
+	type plain Session
+	return unmarshalKeepingUnknown(data, (*plain)(s), &s.extra)
+}
+
+func (s Session) MarshalJSON() ([]byte, error) {
+	type plain Session
+	return marshalWithUnknown(plain(s), s.extra)
+}
+
+func (c *StatusChange) UnmarshalJSON(data []byte) error {
+	type plain StatusChange
+	return unmarshalKeepingUnknown(data, (*plain)(c), &c.extra)
+}
+
+func (c StatusChange) MarshalJSON() ([]byte, error) {
+	type plain StatusChange
+	return marshalWithUnknown(plain(c), c.extra)
+}
+
+func (l *TaskList) UnmarshalJSON(data []byte) error {
+	type plain TaskList
+	return unmarshalKeepingUnknown(data, (*plain)(l), &l.extra)
+}
+
+func (l TaskList) MarshalJSON() ([]byte, error) {
+	type plain TaskList
+	return marshalWithUnknown(plain(l), l.extra)
 }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// listOutput is the structure 'tgo list --json' prints. It is kept separate
+// from TaskList so the storage format can change without breaking scripts.
+type listOutput struct {
+	Name        string       `json:"name"`
+	Title       string       `json:"title"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Tasks       []taskOutput `json:"tasks"`
+}
+
+type taskOutput struct {
+	Number         int                  `json:"number"`
+	ID             int64                `json:"id"`
+	ShortID        string               `json:"short_id"`
+	Title          string               `json:"title"`
+	Status         TaskStatus           `json:"status"`
+	Comment        string               `json:"comment"`
+	Tags           []string             `json:"tags"`
+	Priority       TaskPriority         `json:"priority"`
+	TrackedSeconds int64                `json:"tracked_seconds"`
+	Tracked        string               `json:"tracked"`
+	RunningSince   *time.Time           `json:"running_since,omitempty"`
+	Sessions       int                  `json:"sessions"`
+	PomodorosToday int                  `json:"pomodoros_today"`
+	CreatedAt      time.Time            `json:"created_at"`
+	CompletedAt    *time.Time           `json:"completed_at,omitempty"`
+	DueAt          *time.Time           `json:"due_at,omitempty"`
+	Overdue        bool                 `json:"overdue"`
+	Recur          string               `json:"recur,omitempty"`
+	WaitingOn      string               `json:"waiting_on,omitempty"`
+	FollowUpAt     *time.Time           `json:"follow_up_at,omitempty"`
+	DependsOn      []string             `json:"depends_on,omitempty"`
+	Blocked        bool                 `json:"blocked"`
+	Children       []taskOutput         `json:"children,omitempty"`
+	History        []statusChangeOutput `json:"history,omitempty"`
+}
+
+type statusChangeOutput struct {
+	At   time.Time  `json:"at"`
+	From TaskStatus `json:"from"`
+	To   TaskStatus `json:"to"`
+}
+
+// newListOutput snapshots taskList at now. Tracked time includes the running
+// session of an active task.
+func newListOutput(taskList *TaskList, listName string, now time.Time, filter *taskFilter) listOutput {
+	out := listOutput{
+		Name:        listName,
+		Title:       taskList.Title,
+		GeneratedAt: now,
+		UpdatedAt:   taskList.UpdatedAt,
+		Tasks:       []taskOutput{},
+	}
+
+	for i := range taskList.Items {
+		task := &taskList.Items[i]
+		if !filter.matches(task) {
+			continue
+		}
+		taskOut := newTaskOutput(task, i+1, now)
+		for _, id := range task.DependsOn {
+			taskOut.DependsOn = append(taskOut.DependsOn, shortTaskID(id))
+		}
+		taskOut.Blocked = isBlocked(taskList, task)
+		out.Tasks = append(out.Tasks, taskOut)
+	}
+	return out
+}
+
+// newTaskOutput snapshots a task. Subtasks are numbered within their parent.
+func newTaskOutput(task *Task, number int, now time.Time) taskOutput {
+	tracked := task.TrackedDuration(now)
+	out := taskOutput{
+		Number:         number,
+		ID:             task.ID,
+		ShortID:        task.ShortID(),
+		Title:          task.Title,
+		Status:         task.Status,
+		Comment:        task.Comment,
+		Tags:           append([]string{}, task.Tags...),
+		Priority:       task.Priority,
+		TrackedSeconds: int64(time.Duration(tracked).Seconds()),
+		Tracked:        formatDuration(tracked),
+		RunningSince:   task.ActiveStartTime,
+		Sessions:       len(task.Sessions),
+		PomodorosToday: task.pomodorosOn(now),
+		CreatedAt:      task.CreatedAt,
+		CompletedAt:    task.CompletedAt,
+		DueAt:          task.DueAt,
+		Overdue:        task.IsOverdue(now),
+		Recur:          task.Recur,
+		WaitingOn:      task.WaitingOn,
+		FollowUpAt:     task.FollowUpAt,
+	}
+	for _, change := range task.History {
+		out.History = append(out.History, statusChangeOutput{At: change.At, From: change.From, To: change.To})
+	}
+	for j := range task.Children {
+		out.Children = append(out.Children, newTaskOutput(&task.Children[j], j+1, now))
+	}
+	return out
+}
+
+func writeTaskList(w io.Writer, taskList *TaskList, listName string, format string, filter *taskFilter) error {
+	switch format {
+	case "", formatText:
+		for _, line := range getTaskListLines(taskList, listName, 0, filter) {
+			fmt.Fprintln(w, line)
+		}
+		return nil
+	case formatJSON:
+		data, err := json.MarshalIndent(newListOutput(taskList, listName, time.Now(), filter), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	default:
+		return fmt.Errorf("unknown format '%s', use %s or %s", format, formatText, formatJSON)
+	}
+}
+
+// timerOutput is one entry of 'tgo status --json'.
+type timerOutput struct {
+	List           string    `json:"list"`
+	ID             int64     `json:"id"`
+	Ref            string    `json:"ref"`
+	Title          string    `json:"title"`
+	RunningSince   time.Time `json:"running_since"`
+	RunningSeconds int64     `json:"running_seconds"`
+	TrackedSeconds int64     `json:"tracked_seconds"`
+}
+
+func writeStatus(w io.Writer, timers []runningTimer, format string) error {
+	now := time.Now()
+	switch format {
+	case "", formatText:
+		for _, line := range getStatusLines(timers, now) {
+			fmt.Fprintln(w, line)
+		}
+		return nil
+	case formatJSON:
+		out := []timerOutput{}
+		for _, timer := range timers {
+			out = append(out, timerOutput{
+				List:           timer.listName,
+				ID:             timer.task.ID,
+				Ref:            anyTaskRef(timer.taskList, timer.task),
+				Title:          timer.task.Title,
+				RunningSince:   *timer.task.ActiveStartTime,
+				RunningSeconds: int64(now.Sub(*timer.task.ActiveStartTime).Seconds()),
+				TrackedSeconds: int64(time.Duration(timer.task.TrackedDuration(now)).Seconds()),
+			})
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	default:
+		return fmt.Errorf("unknown format '%s', use %s or %s", format, formatText, formatJSON)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"time"
+)
+
+// A pomodoro runs focus blocks on a task with breaks in between. Each focus
+// block is a normal session on the task, one that ran its full length is
+// marked as a pomodoro.
+
+const (
+	defaultPomoFocus     = 25 * time.Minute
+	defaultPomoBreak     = 5 * time.Minute
+	defaultPomoLongBreak = 15 * time.Minute
+	// Every pomosPerLongBreak-th break is a long one.
+	pomosPerLongBreak = 4
+)
+
+func (c *Config) pomoFocus() time.Duration {
+	return settingDuration(c.PomoFocus, defaultPomoFocus, false)
+}
+
+func (c *Config) pomoBreak() time.Duration {
+	return settingDuration(c.PomoBreak, defaultPomoBreak, false)
+}
+
+func (c *Config) pomoLongBreak() time.Duration {
+	return settingDuration(c.PomoLongBreak, defaultPomoLongBreak, false)
+}
+
+// pomodorosOn counts the pomodoros completed on the task and its subtasks on
+// the day of day.
+func (t *Task) pomodorosOn(day time.Time) int {
+	count := 0
+	for _, session := range t.Sessions {
+		if session.Pomodoro && startOfDay(session.StartTime.In(day.Location())).Equal(startOfDay(day)) {
+			count++
+		}
+	}
+	for i := range t.Children {
+		count += t.Children[i].pomodorosOn(day)
+	}
+	return count
+}
+
+// startAnyTimer (re)starts the timer of the task or subtask with id, so a
+// focus block starts with a session of its own.
+func startAnyTimer(taskList *TaskList, id int64) error {
+	now := time.Now()
+	for i := range taskList.Items {
+		task := &taskList.Items[i]
+		if task.ID == id {
+			stopTaskTimer(task, now)
+			return toggleTaskTimer(taskList, i+1)
+		}
+		for j := range task.Children {
+			if task.Children[j].ID == id {
+				stopTaskTimer(&task.Children[j], now)
+				return toggleSubtaskTimer(taskList, i+1, j+1)
+			}
+		}
+	}
+	return fmt.Errorf("task no longer exists")
+}
+
+// endFocus stops the timer of a focus block at end. A block that ran its
+// full length counts as a pomodoro.
+func endFocus(taskList *TaskList, id int64, end time.Time, completed bool) error {
+	task, err := findAnyTask(taskList, id)
+	if err != nil {
+		return err
+	}
+	if task.ActiveStartTime == nil {
+		return fmt.Errorf("'%s' was stopped in the meantime", task.Title)
+	}
+
+	if end.Sub(*task.ActiveStartTime) < time.Second {
+		// Stopped right away, there is nothing to record.
+		task.ActiveStartTime = nil
+		task.setStatus(task.openStatus(), end)
+		fmt.Printf("[|] Pomodoro stopped: %s\n", task.Title)
+		return nil
+	}
+	stopTaskTimer(task, end)
+	session := &task.Sessions[len(task.Sessions)-1]
+	session.Pomodoro = completed
+	if completed {
+		fmt.Printf("[x] Pomodoro done: %s [Today: %d]\n", task.Title, task.pomodorosOn(end))
+	} else {
+		fmt.Printf("[|] Pomodoro stopped: %s [Session: %s]\n", task.Title, formatDuration(session.Duration))
+	}
+	return nil
+}
+
+// pomodoro is a pomodoro running on a task of the list.
+type pomodoro struct {
+	config   *Config
+	store    Store
+	listName string
+	taskList *TaskList
+	taskID   int64
+	title    string
+	// draw shows the countdown, it is called every second.
+	draw func(footer string)
+}
+
+// run alternates focus blocks and breaks until the user presses Enter or
+// Ctrl+C.
+func (p *pomodoro) run() error {
+	for count := 1; ; count++ {
+		err := updateTimer(p.config, p.store, p.listName, p.taskList, func(taskList *TaskList) error {
+			return startAnyTimer(taskList, p.taskID)
+		})
+		if err != nil {
+			return err
+		}
+
+		focusEnd := time.Now().Add(p.config.pomoFocus())
+		completed := p.countdown(fmt.Sprintf("Pomodoro %d: focus on %s", count, p.title), focusEnd)
+		end := focusEnd
+		if !completed {
+			end = time.Now()
+		}
+		err = updateTasks(p.store, p.listName, p.taskList, func(taskList *TaskList) error {
+			return endFocus(taskList, p.taskID, end, completed)
+		})
+		if err != nil || !completed {
+			return err
+		}
+
+		breakLength := p.config.pomoBreak()
+		if count%pomosPerLongBreak == 0 {
+			breakLength = p.config.pomoLongBreak()
+		}
+		notify(fmt.Sprintf("Pomodoro %d done, take a %d min break", count, int(breakLength.Minutes())))
+		if !p.countdown(fmt.Sprintf("Break after pomodoro %d", count), time.Now().Add(breakLength)) {
+			return nil
+		}
+		notify(fmt.Sprintf("Break over, back to %s", p.title))
+	}
+}
+
+// countdown draws the time left until end every second. It returns false
+// when the user stopped it with Enter or Ctrl+C.
+func (p *pomodoro) countdown(label string, end time.Time) bool {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	timer := time.NewTimer(time.Until(end))
+	defer timer.Stop()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	defer fmt.Println()
+
+	input := nextLine()
+	for {
+		left := time.Until(end).Round(time.Second)
+		p.draw(fmt.Sprintf(" %s | %02d:%02d left | Enter stops ", label, int(left.Minutes()), int(left.Seconds())%60))
+		select {
+		case <-timer.C:
+			return true
+		case line, ok := <-input:
+			if !ok {
+				// Without stdin only Ctrl+C stops it.
+				input = nil
+				continue
+			}
+			takeLine(line, ok)
+			return false
+		case <-interrupt:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify rings the terminal bell and shows a desktop notification where one
+// is available.
+func notify(message string) {
+	fmt.Print("\a")
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("notify-send", "tgo", message)
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title \"tgo\"", message))
+	default:
+		return
+	}
+	go cmd.Run()
+}
+
+// newPomodoro prepares a pomodoro on the task or <task>.<n> subtask ref.
+func newPomodoro(config *Config, store Store, listName string, taskList *TaskList, ref string, draw func(string)) (*pomodoro, error) {
+	task, err := resolveAnyTask(taskList, ref)
+	if err != nil {
+		return nil, err
+	}
+	if task.IsClosed() {
+		return nil, fmt.Errorf("cannot start a pomodoro on a %s task", task.Status)
+	}
+	return &pomodoro{
+		config:   config,
+		store:    store,
+		listName: listName,
+		taskList: taskList,
+		taskID:   task.ID,
+		title:    task.Title,
+		draw:     draw,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type TaskPriority string
+
+const (
+	PriorityNone   TaskPriority = ""
+	PriorityHigh   TaskPriority = "H"
+	PriorityMedium TaskPriority = "M"
+	PriorityLow    TaskPriority = "L"
+)
+
+func parsePriority(s string) (TaskPriority, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "h", "high", "1":
+		return PriorityHigh, nil
+	case "m", "medium", "2":
+		return PriorityMedium, nil
+	case "l", "low", "3":
+		return PriorityLow, nil
+	case "-", "none":
+		return PriorityNone, nil
+	}
+	return PriorityNone, fmt.Errorf("invalid priority '%s', use H, M, L or '-' to clear", s)
+}
+
+// rank orders priorities for sorting, high first and none last.
+func (p TaskPriority) rank() int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityMedium:
+		return 1
+	case PriorityLow:
+		return 2
+	}
+	return 3
+}
+
+func setTaskPriority(taskList *TaskList, index int, priority TaskPriority) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	task.Priority = priority
+	if priority == PriorityNone {
+		fmt.Printf("[~] Priority cleared: %s\n", task.Title)
+	} else {
+		fmt.Printf("[~] Priority %s: %s\n", priority, task.Title)
+	}
+	return nil
+}
+
+// Sort modes for the task view. Tasks keep their number whatever the sort,
+// it only changes the order they're shown in within each section.
+const (
+	sortManual   = "manual"
+	sortPriority = "priority"
+	sortDue      = "due"
+	sortCreated  = "created"
+	sortTime     = "time"
+)
+
+var sortModes = []string{sortManual, sortPriority, sortDue, sortCreated, sortTime}
+
+func parseSortMode(s string) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(s))
+	if slices.Contains(sortModes, mode) {
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid sort '%s', use %s", s, strings.Join(sortModes, ", "))
+}
+
+func setSortMode(taskList *TaskList, mode string) {
+	taskList.Sort = mode
+	if mode == sortManual {
+		taskList.Sort = ""
+	}
+	fmt.Printf("[~] Sorted by %s\n", mode)
+}
+
+// sortMode is the list's sort, manual if none was chosen.
+func (taskList *TaskList) sortMode() string {
+	if taskList.Sort == "" {
+		return sortManual
+	}
+	return taskList.Sort
+}
+
+// sortTaskIndices orders the 0-based task indices by the list's sort mode.
+// Ties keep their manual order.
+func sortTaskIndices(taskList *TaskList, indices []int, now time.Time) {
+	var compare func(a, b *Task) int
+	switch taskList.sortMode() {
+	case sortPriority:
+		compare = func(a, b *Task) int {
+			return a.Priority.rank() - b.Priority.rank()
+		}
+	case sortDue:
+		compare = func(a, b *Task) int {
+			switch {
+			case a.DueAt == nil && b.DueAt == nil:
+				return 0
+			case a.DueAt == nil:
+				return 1
+			case b.DueAt == nil:
+				return -1
+			}
+			return dueDeadline(*a.DueAt).Compare(dueDeadline(*b.DueAt))
+		}
+	case sortCreated:
+		compare = func(a, b *Task) int {
+			return b.CreatedAt.Compare(a.CreatedAt)
+		}
+	case sortTime:
+		compare = func(a, b *Task) int {
+			ad, bd := a.TrackedDuration(now), b.TrackedDuration(now)
+			switch {
+			case ad > bd:
+				return -1
+			case ad < bd:
+				return 1
+			}
+			return 0
+		}
+	default:
+		return
+	}
+
+	slices.SortStableFunc(indices, func(i, j int) int {
+		return compare(&taskList.Items[i], &taskList.Items[j])
+	})
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence rules are stored as text in their canonical form:
+//
+//	daily
+//	weekdays          Monday to Friday
+//	weekly            same weekday as the last due date
+//	weekly mon,thu
+//	monthly           same day of the month as the first due date
+//	monthly 31        on the 31st, or the last day of shorter months
+//	every 3d          3 days after the task was completed
+//
+// All but "every" follow a fixed schedule based on the previous due date.
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseRecurrence checks a rule and returns it in canonical form.
+func parseRecurrence(s string) (string, error) {
+	fields := strings.Fields(strings.ToLower(strings.ReplaceAll(s, ",", " ")))
+	invalid := fmt.Errorf("invalid repeat rule '%s', use daily, weekdays, weekly [mon,thu], monthly [day] or every <n>d", s)
+	if len(fields) == 0 {
+		return "", invalid
+	}
+
+	switch fields[0] {
+	case "daily", "weekdays":
+		if len(fields) > 1 {
+			return "", invalid
+		}
+		return fields[0], nil
+	case "monthly":
+		if len(fields) == 1 {
+			return "monthly", nil
+		}
+		day, err := strconv.Atoi(fields[1])
+		if err != nil || len(fields) > 2 || day < 1 || day > 31 {
+			return "", invalid
+		}
+		return fmt.Sprintf("monthly %d", day), nil
+	case "weekly":
+		days, err := parseWeekdays(fields[1:])
+		if err != nil {
+			return "", invalid
+		}
+		if len(days) == 0 {
+			return "weekly", nil
+		}
+		names := make([]string, len(days))
+		for i, day := range days {
+			names[i] = weekdayNames[day]
+		}
+		return "weekly " + strings.Join(names, ","), nil
+	case "every":
+		n, err := parseEveryDays(fields[1:])
+		if err != nil || n < 1 {
+			return "", invalid
+		}
+		return fmt.Sprintf("every %dd", n), nil
+	}
+	return "", invalid
+}
+
+// parseWeekdays parses day names into weekdays, sorted Sunday first.
+func parseWeekdays(names []string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, name := range names {
+		day, ok := weekdays[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown day '%s'", name)
+		}
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	slices.Sort(days)
+	return days, nil
+}
+
+// parseEveryDays reads "3d", "3 days" or "3".
+func parseEveryDays(fields []string) (int, error) {
+	switch {
+	case len(fields) == 1:
+		return strconv.Atoi(strings.TrimSuffix(fields[0], "d"))
+	case len(fields) == 2 && (fields[1] == "days" || fields[1] == "day"):
+		return strconv.Atoi(fields[0])
+	}
+	return 0, fmt.Errorf("invalid interval")
+}
+
+// nextDue returns the due date of the instance after one that was due at
+// due (nil if it had none) and completed at completed. Fixed schedules skip
+// dates that are already over, so a late completion doesn't leave an
+// overdue instance behind.
+func nextDue(rule string, due *time.Time, completed time.Time) time.Time {
+	fields := strings.Fields(rule)
+
+	if fields[0] == "every" {
+		n, _ := parseEveryDays(fields[1:])
+		base := completed
+		if due != nil {
+			base = withTimeOf(completed, *due)
+		}
+		return startOfDayIfAllDay(base.AddDate(0, 0, n), due)
+	}
+
+	base := startOfDay(completed)
+	if due != nil {
+		base = *due
+	}
+	var days []time.Weekday
+	anchor := base.Day()
+	if len(fields) > 1 {
+		if fields[0] == "monthly" {
+			anchor, _ = strconv.Atoi(fields[1])
+		} else {
+			days, _ = parseWeekdays(strings.Split(fields[1], ","))
+		}
+	}
+
+	today := startOfDay(completed)
+	next := base
+	for {
+		next = nextOccurrence(fields[0], days, anchor, next)
+		if !startOfDay(next).Before(today) {
+			return next
+		}
+	}
+}
+
+// nextOccurrence returns the date after from on a fixed schedule. days are
+// the weekdays of a weekly rule, anchor the day of the month of a monthly
+// one, moved to the last day of months that are shorter.
+func nextOccurrence(kind string, days []time.Weekday, anchor int, from time.Time) time.Time {
+	switch kind {
+	case "daily":
+		return from.AddDate(0, 0, 1)
+	case "weekdays":
+		next := from.AddDate(0, 0, 1)
+		for next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	case "weekly":
+		if len(days) == 0 {
+			return from.AddDate(0, 0, 7)
+		}
+		next := from.AddDate(0, 0, 1)
+		for !slices.Contains(days, next.Weekday()) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	default: // monthly
+		year, month, _ := from.Date()
+		first := time.Date(year, month+1, 1, from.Hour(), from.Minute(), 0, 0, from.Location())
+		day := min(anchor, first.AddDate(0, 1, -1).Day())
+		return first.AddDate(0, 0, day-1)
+	}
+}
+
+// withTimeOf returns day at the time of day of clock.
+func withTimeOf(day, clock time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+}
+
+// startOfDayIfAllDay keeps a task that had no due time, or no due date at
+// all, due for the whole day.
+func startOfDayIfAllDay(t time.Time, due *time.Time) time.Time {
+	if due == nil || dueAllDay(*due) {
+		return startOfDay(t)
+	}
+	return t
+}
+
+func setTaskRecurrence(taskList *TaskList, index int, rule string) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	task.Recur = rule
+	if rule == "" {
+		fmt.Printf("[~] Stopped repeating: %s\n", task.Title)
+	} else {
+		fmt.Printf("[~] Repeats %s: %s\n", rule, task.Title)
+	}
+	return nil
+}
+
+// parseRecurrenceArg parses the rule given to the repeat command, '-' stops
+// the task from repeating.
+func parseRecurrenceArg(s string) (string, error) {
+	if strings.TrimSpace(s) == "-" {
+		return "", nil
+	}
+	return parseRecurrence(s)
+}
+
+// spawnNextInstance adds the next instance of a recurring task that was just
+// completed. The completed instance stays in the list with its sessions and
+// no longer repeats itself.
+func spawnNextInstance(taskList *TaskList, task *Task, completed time.Time) {
+	rule := task.Recur
+	if rule == "monthly" {
+		// Pin the day, a task due on the 31st goes back to it after a
+		// shorter month.
+		day := completed.Day()
+		if task.DueAt != nil {
+			day = task.DueAt.Day()
+		}
+		rule = fmt.Sprintf("monthly %d", day)
+	}
+	due := nextDue(rule, task.DueAt, completed)
+	next := Task{
+		ID:        newTaskID(taskList),
+		Title:     task.Title,
+		Status:    StatusPending,
+		Comment:   task.Comment,
+		Tags:      slices.Clone(task.Tags),
+		Priority:  task.Priority,
+		Sessions:  []Session{},
+		DueAt:     &due,
+		Recur:     rule,
+		CreatedAt: completed,
+	}
+	children := task.Children
+	task.Recur = ""
+	task.NextInstance = next.ID
+
+	// Subtasks start over unchecked. They get their IDs once the new task
+	// is in the list, so newTaskID sees the ones already taken.
+	taskList.Items = append(taskList.Items, next)
+	spawned := &taskList.Items[len(taskList.Items)-1]
+	for _, child := range children {
+		spawned.Children = append(spawned.Children, Task{
+			ID:        newTaskID(taskList),
+			Title:     child.Title,
+			Status:    StatusPending,
+			Sessions:  []Session{},
+			CreatedAt: completed,
+		})
+	}
+	fmt.Printf("[+] Next: %s [Due: %s]\n", next.Title, formatDue(due, completed))
+}
+
+// takeBackNextInstance undoes spawnNextInstance when the completed or
+// cancelled instance with id is reopened: it repeats again, and the next
+// instance is removed unless it was already worked on.
+func takeBackNextInstance(taskList *TaskList, id int64) {
+	index, err := findTaskIndex(taskList, id)
+	if err != nil || taskList.Items[index-1].NextInstance == 0 {
+		return
+	}
+	task := &taskList.Items[index-1]
+	nextID := task.NextInstance
+	task.NextInstance = 0
+
+	nextIndex, err := findTaskIndex(taskList, nextID)
+	if err != nil {
+		return
+	}
+	next := &taskList.Items[nextIndex-1]
+	if !next.untouched() {
+		fmt.Printf("[i] Kept the next instance, it was already worked on: %s\n", next.Title)
+		return
+	}
+	task.Recur = next.Recur
+	taskList.Items = slices.Delete(taskList.Items, nextIndex-1, nextIndex)
+	dropDependency(taskList, nextID)
+	fmt.Printf("[-] Removed the next instance, repeats %s again\n", task.Recur)
+}
+
+// untouched reports whether nothing happened to a task since it was added.
+func (t *Task) untouched() bool {
+	if t.Status != StatusPending || len(t.Sessions) > 0 || len(t.History) > 0 {
+		return false
+	}
+	for i := range t.Children {
+		if !t.Children[i].untouched() {
+			return false
+		}
+	}
+	return true
+}
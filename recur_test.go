@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "daily", want: "daily"},
+		{in: "Weekdays", want: "weekdays"},
+		{in: "weekly", want: "weekly"},
+		{in: "weekly thu, mon", want: "weekly mon,thu"},
+		{in: "weekly mon,mon", want: "weekly mon"},
+		{in: "monthly", want: "monthly"},
+		{in: "monthly 31", want: "monthly 31"},
+		{in: "every 3d", want: "every 3d"},
+		{in: "every 3 days", want: "every 3d"},
+		{in: "", err: true},
+		{in: "daily 2", err: true},
+		{in: "weekly someday", err: true},
+		{in: "monthly 0", err: true},
+		{in: "monthly 32", err: true},
+		{in: "every 0d", err: true},
+		{in: "yearly", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRecurrence(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseRecurrence(%q) = %q, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseRecurrence(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNextDue(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name string
+		rule string
+		due  time.Time
+		// want are the due dates of the following instances, each one
+		// completed on the day it is due.
+		want []time.Time
+	}{
+		{
+			name: "month end comes back after short months",
+			rule: "monthly 31",
+			due:  date(2026, time.January, 31),
+			want: []time.Time{date(2026, time.February, 28), date(2026, time.March, 31), date(2026, time.April, 30), date(2026, time.May, 31)},
+		},
+		{
+			name: "leap year",
+			rule: "monthly 31",
+			due:  date(2028, time.January, 31),
+			want: []time.Time{date(2028, time.February, 29), date(2028, time.March, 31)},
+		},
+		{
+			name: "29th in a leap year and the year after",
+			rule: "monthly 29",
+			due:  date(2028, time.December, 29),
+			want: []time.Time{date(2029, time.January, 29), date(2029, time.February, 28), date(2029, time.March, 29)},
+		},
+		{
+			name: "unanchored monthly keeps the day of the due date",
+			rule: "monthly",
+			due:  date(2026, time.March, 15),
+			want: []time.Time{date(2026, time.April, 15)},
+		},
+		{
+			name: "weekdays skip the weekend",
+			rule: "weekdays",
+			due:  date(2026, time.October, 16),
+			want: []time.Time{date(2026, time.October, 19), date(2026, time.October, 20)},
+		},
+		{
+			name: "weekly on given days",
+			rule: "weekly mon,thu",
+			due:  date(2026, time.October, 12),
+			want: []time.Time{date(2026, time.October, 15), date(2026, time.October, 19)},
+		},
+		{
+			name: "every n days",
+			rule: "every 3d",
+			due:  date(2026, time.February, 27),
+			want: []time.Time{date(2026, time.March, 2), date(2026, time.March, 5)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due := tt.due
+			for i, want := range tt.want {
+				next := nextDue(tt.rule, &due, due)
+				if !next.Equal(want) {
+					t.Fatalf("instance %d: got %s, want %s", i+1, next.Format("2006-01-02"), want.Format("2006-01-02"))
+				}
+				due = next
+			}
+		})
+	}
+}
+
+func TestNextDueSkipsMissedDates(t *testing.T) {
+	due := time.Date(2026, time.January, 31, 0, 0, 0, 0, time.Local)
+	completed := time.Date(2026, time.April, 2, 10, 0, 0, 0, time.Local)
+	want := time.Date(2026, time.April, 30, 0, 0, 0, 0, time.Local)
+	if next := nextDue("monthly 31", &due, completed); !next.Equal(want) {
+		t.Errorf("got %s, want %s", next.Format("2006-01-02"), want.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// currentSchemaVersion is the TaskList file format written by this version.
+// Files without a schema_version are version 0.
+const currentSchemaVersion = 1
+
+// migrations[v] upgrades a task list document from schema version v to v+1.
+// Migrations work on the raw document so they never drop fields they don't
+// know about.
+var migrations = []func(doc map[string]json.RawMessage) error{
+	migrateV0ToV1,
+}
+
+// migrateTaskList upgrades data to currentSchemaVersion. Documents written by
+// a newer tgo are returned unchanged.
+func migrateTaskList(data []byte) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	version := 0
+	if raw, ok := doc["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("invalid schema_version: %v", err)
+		}
+	}
+	if version >= currentSchemaVersion {
+		return data, nil
+	}
+
+	for ; version < currentSchemaVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return nil, fmt.Errorf("migrating schema %d to %d: %v", version, version+1, err)
+		}
+		doc["schema_version"] = json.RawMessage(fmt.Sprint(version + 1))
+	}
+	return json.Marshal(doc)
+}
+
+func migrateV0ToV1(doc map[string]json.RawMessage) error {
+	if raw, ok := doc["items"]; !ok || string(raw) == "null" {
+		doc["items"] = json.RawMessage("[]")
+	}
+	return nil
+}
+
+// unmarshalKeepingUnknown decodes data into v and stores every field v has
+// no json tag for in extra, so it can be written back by marshalWithUnknown.
+func unmarshalKeepingUnknown(data []byte, v any, extra *map[string]json.RawMessage) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for name := range knownJSONFields(reflect.TypeOf(v).Elem()) {
+		delete(fields, name)
+	}
+
+	*extra = nil
+	if len(fields) > 0 {
+		*extra = fields
+	}
+	return nil
+}
+
+// marshalWithUnknown encodes v and appends the fields in extra that v
+// doesn't set itself.
+func marshalWithUnknown(v any, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	known := knownJSONFields(reflect.TypeOf(v))
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, name := range names {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extra[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func knownJSONFields(t reflect.Type) map[string]bool {
+	known := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		known[name] = true
+	}
+	return known
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMigrateTaskList(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		items string
+	}{
+		{name: "version 0 without items", data: `{"title":"Work","color":"red"}`, items: `[]`},
+		{name: "version 0 with null items", data: `{"title":"Work","items":null,"color":"red"}`, items: `[]`},
+		{
+			name:  "version 0 with items",
+			data:  `{"title":"Work","items":[{"id":1,"title":"A","estimate":"2h"}],"color":"red"}`,
+			items: `[{"id":1,"title":"A","estimate":"2h"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, err := migrateTaskList([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc map[string]json.RawMessage
+			if err := json.Unmarshal(migrated, &doc); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(doc["schema_version"]); got != "1" {
+				t.Errorf("schema_version = %s, want 1", got)
+			}
+			if got := string(doc["items"]); got != tt.items {
+				t.Errorf("items = %s, want %s", got, tt.items)
+			}
+			if got := string(doc["color"]); got != `"red"` {
+				t.Errorf("unknown field color = %s, want \"red\"", got)
+			}
+		})
+	}
+}
+
+func TestMigrateTaskListFromNewerVersion(t *testing.T) {
+	data := `{"schema_version":7,"title":"Work","items":null}`
+	migrated, err := migrateTaskList([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(migrated) != data {
+		t.Errorf("got %s, want it unchanged", migrated)
+	}
+}
+
+func TestDecodeTaskListKeepsUnknownFields(t *testing.T) {
+	data := `{"title":"Work","color":"red","items":[{"id":1,"title":"A","status":"pending","estimate":"2h"}]}`
+	taskList, err := decodeTaskList([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taskList.SchemaVersion != currentSchemaVersion {
+		t.Errorf("schema version = %d, want %d", taskList.SchemaVersion, currentSchemaVersion)
+	}
+
+	saved, err := json.Marshal(taskList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Color string `json:"color"`
+		Items []struct {
+			Estimate string `json:"estimate"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(saved, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Color != "red" || len(doc.Items) != 1 || doc.Items[0].Estimate != "2h" {
+		t.Errorf("unknown fields lost: %s", saved)
+	}
+}
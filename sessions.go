@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sessions are the record of tracked time, TotalDuration is always their
+// sum. Sessions of the tasks in a list never overlap, neither each other nor
+// a running timer.
+
+func newSession(start, end time.Time) Session {
+	return Session{StartTime: start, EndTime: end, Duration: end.Sub(start).Nanoseconds()}
+}
+
+// recomputeDuration sets TotalDuration to the sum of the task's sessions.
+func (t *Task) recomputeDuration() {
+	var total int64
+	for _, session := range t.Sessions {
+		total += session.Duration
+	}
+	t.TotalDuration = total
+}
+
+// parseClockRange parses "09:00-10:30" on day. A range ending before it
+// starts runs past midnight.
+func parseClockRange(s string, day time.Time) (time.Time, time.Time, error) {
+	invalid := fmt.Errorf("invalid time range '%s', use e.g. 09:00-10:30", s)
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return time.Time{}, time.Time{}, invalid
+	}
+	start, err := parseClock(from, day)
+	if err != nil {
+		return time.Time{}, time.Time{}, invalid
+	}
+	end, err := parseClock(to, day)
+	if err != nil {
+		return time.Time{}, time.Time{}, invalid
+	}
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+// parseClock returns day at the "15:04" time s.
+func parseClock(s string, day time.Time) (time.Time, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time '%s', use e.g. 09:45", s)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location()), nil
+}
+
+// parseLogArgs reads the time given to the log command: a duration like
+// "45m" or "1h30m" that ends now, or a range like "09:00-10:30", optionally
+// after a day such as "yesterday" or "mon".
+func parseLogArgs(args []string, now time.Time) (time.Time, time.Time, error) {
+	if len(args) == 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("time required, e.g. 45m or 09:00-10:30")
+	}
+
+	spec := args[len(args)-1]
+	if !strings.Contains(spec, ":") {
+		if len(args) > 1 {
+			return time.Time{}, time.Time{}, fmt.Errorf("a day only goes with a time range, e.g. yesterday 09:00-10:30")
+		}
+		duration, err := time.ParseDuration(spec)
+		if err != nil || duration <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid duration '%s', use e.g. 45m or 1h30m", spec)
+		}
+		return now.Add(-duration), now, nil
+	}
+
+	day := now
+	if len(args) > 1 {
+		var err error
+		if day, err = parsePastDay(strings.Join(args[:len(args)-1], " "), now); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return parseClockRange(spec, day)
+}
+
+// parsePastDay reads the day time was worked on: today, yesterday, a
+// weekday (the last one, today included), -3d or 2026-11-03.
+func parsePastDay(s string, now time.Time) (time.Time, error) {
+	day := strings.ToLower(strings.TrimSpace(s))
+	today := startOfDay(now)
+	if weekday, ok := weekdays[day]; ok {
+		return today.AddDate(0, 0, -((int(now.Weekday()) - int(weekday) + 7) % 7)), nil
+	}
+	switch day {
+	case "today", "tod":
+		return today, nil
+	case "yesterday", "yest":
+		return today.AddDate(0, 0, -1), nil
+	}
+	if strings.HasPrefix(day, "-") && strings.HasSuffix(day, "d") {
+		if n, err := strconv.Atoi(day[1 : len(day)-1]); err == nil && n >= 0 {
+			return today.AddDate(0, 0, -n), nil
+		}
+	}
+	date, err := time.ParseInLocation("2006-01-02", day, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse day '%s', use e.g. yesterday, mon, -2d or 2026-11-03", s)
+	}
+	return date, nil
+}
+
+// findOverlap returns the task whose tracked time overlaps start-end, if
+// any. skip is left out, so a session can be checked against the others.
+func findOverlap(taskList *TaskList, start, end time.Time, skip *Session, now time.Time) *Task {
+	overlaps := func(task *Task) bool {
+		for i := range task.Sessions {
+			session := &task.Sessions[i]
+			if session != skip && session.StartTime.Before(end) && start.Before(session.EndTime) {
+				return true
+			}
+		}
+		return task.ActiveStartTime != nil && task.ActiveStartTime.Before(end) && start.Before(now)
+	}
+
+	for i := range taskList.Items {
+		task := &taskList.Items[i]
+		if overlaps(task) {
+			return task
+		}
+		for j := range task.Children {
+			if overlaps(&task.Children[j]) {
+				return &task.Children[j]
+			}
+		}
+	}
+	return nil
+}
+
+// checkSession refuses sessions in the future and sessions overlapping time
+// that is already tracked, in taskList or in any of the other lists.
+func checkSession(taskList *TaskList, others []*TaskList, start, end time.Time, skip *Session, now time.Time) error {
+	if end.After(now) {
+		return fmt.Errorf("cannot log time in the future")
+	}
+	if other := findOverlap(taskList, start, end, skip, now); other != nil {
+		return fmt.Errorf("%s overlaps time tracked on '%s'", formatSessionRange(start, end), other.Title)
+	}
+	for _, otherList := range others {
+		if other := findOverlap(otherList, start, end, nil, now); other != nil {
+			return fmt.Errorf("%s overlaps time tracked on '%s' in %s", formatSessionRange(start, end), other.Title, otherList.Title)
+		}
+	}
+	return nil
+}
+
+// loadOtherLists loads every list of the store but listName, to check
+// sessions against the time tracked there.
+func loadOtherLists(store Store, listName string) ([]*TaskList, error) {
+	listNames, err := store.Lists()
+	if err != nil {
+		return nil, err
+	}
+
+	var others []*TaskList
+	for _, name := range listNames {
+		if name == listName {
+			continue
+		}
+		taskList, err := store.Load(name)
+		if err != nil {
+			return nil, fmt.Errorf("load error: %s: %v", name, err)
+		}
+		others = append(others, taskList)
+	}
+	return others, nil
+}
+
+// addSession inserts session in start order.
+func (t *Task) addSession(session Session) {
+	i, _ := slices.BinarySearchFunc(t.Sessions, session, func(a, b Session) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	t.Sessions = slices.Insert(t.Sessions, i, session)
+	t.recomputeDuration()
+}
+
+// logTime records time worked on a task without running its timer.
+func logTime(taskList *TaskList, others []*TaskList, task *Task, start, end time.Time) error {
+	now := time.Now()
+	if err := checkSession(taskList, others, start, end, nil, now); err != nil {
+		return err
+	}
+
+	task.addSession(newSession(start, end))
+	if task.Status == StatusPending {
+		task.setStatus(StatusPaused, now)
+	}
+	fmt.Printf("[+] Logged %s on %s (%s) [Total: %s]\n",
+		formatDuration(end.Sub(start).Nanoseconds()), task.Title, formatSessionRange(start, end), task.GetFormattedDuration())
+	return nil
+}
+
+// resolveSession returns the session with the 1-based number n.
+func resolveSession(task *Task, n string) (int, error) {
+	number, err := strconv.Atoi(n)
+	if err != nil || number < 1 || number > len(task.Sessions) {
+		if len(task.Sessions) == 0 {
+			return 0, fmt.Errorf("'%s' has no sessions", task.Title)
+		}
+		return 0, fmt.Errorf("invalid session number. Use 1-%d", len(task.Sessions))
+	}
+	return number, nil
+}
+
+// editSession changes the times of a session: a range, which keeps the day
+// the session started on unless a day is given, or a new length.
+func editSession(taskList *TaskList, others []*TaskList, task *Task, number int, args []string) error {
+	session := &task.Sessions[number-1]
+	now := time.Now()
+
+	var start, end time.Time
+	var err error
+	if spec := args[len(args)-1]; len(args) == 1 && !strings.Contains(spec, ":") {
+		duration, err := time.ParseDuration(spec)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("invalid duration '%s', use e.g. 45m or 1h30m", spec)
+		}
+		start, end = session.StartTime, session.StartTime.Add(duration)
+	} else {
+		day := session.StartTime.Local()
+		if len(args) > 1 {
+			if day, err = parsePastDay(strings.Join(args[:len(args)-1], " "), now); err != nil {
+				return err
+			}
+		}
+		if start, end, err = parseClockRange(spec, day); err != nil {
+			return err
+		}
+	}
+	if err := checkSession(taskList, others, start, end, session, now); err != nil {
+		return err
+	}
+
+	task.Sessions = slices.Delete(task.Sessions, number-1, number)
+	task.addSession(newSession(start, end))
+	fmt.Printf("[~] Session changed to %s on %s [Total: %s]\n", formatSessionRange(start, end), task.Title, task.GetFormattedDuration())
+	return nil
+}
+
+// splitSession cuts a session in two at the given time of day.
+func splitSession(task *Task, number int, clock string) error {
+	session := task.Sessions[number-1]
+	at, err := parseClock(clock, session.StartTime.Local())
+	if err != nil {
+		return err
+	}
+	if !at.After(session.StartTime) {
+		at = at.AddDate(0, 0, 1)
+	}
+	if !at.Before(session.EndTime) {
+		return fmt.Errorf("%s is not within %s", clock, formatSessionRange(session.StartTime, session.EndTime))
+	}
+
+	task.Sessions[number-1] = newSession(session.StartTime, at)
+	task.addSession(newSession(at, session.EndTime))
+	fmt.Printf("[~] Split session on %s: %s and %s\n", task.Title,
+		formatSessionRange(session.StartTime, at), formatSessionRange(at, session.EndTime))
+	return nil
+}
+
+func deleteSession(task *Task, number int) error {
+	session := task.Sessions[number-1]
+	task.Sessions = slices.Delete(task.Sessions, number-1, number)
+	task.recomputeDuration()
+	fmt.Printf("[-] Deleted session %s from %s [Total: %s]\n",
+		formatSessionRange(session.StartTime, session.EndTime), task.Title, task.GetFormattedDuration())
+	return nil
+}
+
+// changeSession runs "<n> set [day] <range>", "<n> split <time>" or
+// "<n> rm" on the sessions of task. others are the other lists of the store,
+// see checkSession.
+func changeSession(taskList *TaskList, others []*TaskList, task *Task, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("use <n> set [day] <from>-<to>, <n> split <time> or <n> rm")
+	}
+	number, err := resolveSession(task, args[0])
+	if err != nil {
+		return err
+	}
+
+	switch args[1] {
+	case "set":
+		if len(args) < 3 {
+			return fmt.Errorf("time range required, e.g. 09:00-10:30")
+		}
+		return editSession(taskList, others, task, number, args[2:])
+	case "split":
+		if len(args) != 3 {
+			return fmt.Errorf("time to split at required, e.g. 09:45")
+		}
+		return splitSession(task, number, args[2])
+	case "rm", "delete":
+		return deleteSession(task, number)
+	}
+	return fmt.Errorf("unknown session command '%s', use set, split or rm", args[1])
+}
+
+func formatSessionRange(start, end time.Time) string {
+	start, end = start.Local(), end.Local()
+	if startOfDay(start).Equal(startOfDay(end)) {
+		return fmt.Sprintf("%s %s-%s", start.Format("2006-01-02"), start.Format("15:04"), end.Format("15:04"))
+	}
+	return fmt.Sprintf("%s %s - %s", start.Format("2006-01-02"), start.Format("15:04"), end.Format("2006-01-02 15:04"))
+}
+
+// getSessionLines renders the numbered sessions of a task.
+func getSessionLines(task *Task, now time.Time) []string {
+	lines := []string{fmt.Sprintf("[i] %s (%s) [Total: %s]", task.Title, task.ShortID(), formatDuration(task.TrackedDuration(now)))}
+	if len(task.Sessions) == 0 && task.ActiveStartTime == nil {
+		return append(lines, "    No sessions recorded")
+	}
+	for i, session := range task.Sessions {
+		lines = append(lines, fmt.Sprintf("    %d. %s  %s", i+1,
+			formatSessionRange(session.StartTime, session.EndTime), formatDuration(session.Duration)))
+	}
+	if task.ActiveStartTime != nil {
+		lines = append(lines, fmt.Sprintf("    >  %s-  [Running: %s]",
+			task.ActiveStartTime.Local().Format("2006-01-02 15:04"), formatDuration(now.Sub(*task.ActiveStartTime).Nanoseconds())))
+	}
+	return lines
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A timer is stale when it was probably left running by accident: it runs
+// longer than the stale-after setting or since before midnight.
+
+const defaultStaleAfter = 8 * time.Hour
+
+// staleAfter returns how long a timer may run before it counts as stale, 0
+// if only timers running past midnight do.
+func (c *Config) staleAfter() time.Duration {
+	return settingDuration(c.StaleAfter, defaultStaleAfter, true)
+}
+
+func (t *Task) isStale(now time.Time, threshold time.Duration) bool {
+	if t.ActiveStartTime == nil {
+		return false
+	}
+	if threshold > 0 && now.Sub(*t.ActiveStartTime) > threshold {
+		return true
+	}
+	return t.ActiveStartTime.Before(startOfDay(now))
+}
+
+// staleTimers returns the tasks and subtasks of the list with a stale timer.
+func staleTimers(taskList *TaskList, now time.Time, threshold time.Duration) []*Task {
+	var stale []*Task
+	for i := range taskList.Items {
+		task := &taskList.Items[i]
+		if task.isStale(now, threshold) {
+			stale = append(stale, task)
+		}
+		for j := range task.Children {
+			if task.Children[j].isStale(now, threshold) {
+				stale = append(stale, &task.Children[j])
+			}
+		}
+	}
+	return stale
+}
+
+// lastActivity is the last timer or status action on the task, or when its
+// timer was started. Saves of the list for other tasks don't count.
+func lastActivity(task *Task) time.Time {
+	last := *task.ActiveStartTime
+	if task.LastActivity != nil && task.LastActivity.After(last) {
+		last = *task.LastActivity
+	}
+	if change := lastStatusChange(task); change.After(last) {
+		last = change
+	}
+	return last
+}
+
+const (
+	recoverKeep    = "keep"
+	recoverLast    = "last"
+	recoverAt      = "at"
+	recoverDiscard = "discard"
+)
+
+// recoverTimer resolves a stale timer: keep it running, stop it at the last
+// activity or at a given time, or throw the running session away. others are
+// the other lists of the store, see checkSession.
+func recoverTimer(taskList *TaskList, others []*TaskList, task *Task, action string, at time.Time) error {
+	if task.ActiveStartTime == nil {
+		return fmt.Errorf("'%s' has no running timer", task.Title)
+	}
+	now := time.Now()
+
+	switch action {
+	case recoverKeep:
+		task.touch(now)
+		fmt.Printf("[>] Still running: %s\n", task.Title)
+		return nil
+	case recoverLast:
+		at = lastActivity(task)
+		if !at.After(*task.ActiveStartTime) {
+			return fmt.Errorf("no activity since '%s' was started, stop it at a time or discard it", task.Title)
+		}
+	case recoverAt:
+		if !at.After(*task.ActiveStartTime) || at.After(now) {
+			return fmt.Errorf("stop time must be between %s and now", task.ActiveStartTime.Local().Format("2006-01-02 15:04"))
+		}
+	case recoverDiscard:
+		task.ActiveStartTime = nil
+		task.setStatus(task.openStatus(), now)
+		fmt.Printf("[-] Discarded running session: %s\n", task.Title)
+		return nil
+	default:
+		return fmt.Errorf("unknown action '%s', use keep, last, at <time> or discard", action)
+	}
+
+	// The running session itself doesn't count as an overlap.
+	start := *task.ActiveStartTime
+	task.ActiveStartTime = nil
+	err := checkSession(taskList, others, start, at, nil, now)
+	task.ActiveStartTime = &start
+	if err != nil {
+		return err
+	}
+
+	stopTaskTimer(task, at)
+	fmt.Printf("[|] Stopped at %s: %s [Session: %s] [Total: %s]\n",
+		at.Local().Format("2006-01-02 15:04"),
+		task.Title,
+		formatDuration(task.Sessions[len(task.Sessions)-1].Duration),
+		task.GetFormattedDuration())
+	return nil
+}
+
+// parseRecoverArgs reads "keep", "last", "discard" or "at [day] <time>".
+func parseRecoverArgs(args []string, now time.Time) (string, time.Time, error) {
+	if len(args) == 0 {
+		return "", time.Time{}, fmt.Errorf("action required: keep, last, at [day] <time> or discard")
+	}
+	action := strings.ToLower(args[0])
+	if action != recoverAt {
+		if len(args) > 1 {
+			return "", time.Time{}, fmt.Errorf("'%s' takes no arguments", action)
+		}
+		return action, time.Time{}, nil
+	}
+	at, err := parseStopTime(args[1:], now)
+	return action, at, err
+}
+
+// parseStopTime reads "[day] <time>", e.g. "18:30" or "yesterday 18:30".
+func parseStopTime(args []string, now time.Time) (time.Time, error) {
+	if len(args) == 0 {
+		return time.Time{}, fmt.Errorf("time required, e.g. 18:30 or yesterday 18:30")
+	}
+	day := now
+	if len(args) > 1 {
+		var err error
+		if day, err = parsePastDay(strings.Join(args[:len(args)-1], " "), now); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return parseClock(args[len(args)-1], day)
+}
+
+func staleTimerLine(task *Task, now time.Time) string {
+	return fmt.Sprintf("'%s' (%s) has been running since %s [%s], last activity %s",
+		task.Title, task.ShortID(),
+		task.ActiveStartTime.Local().Format("Mon 2006-01-02 15:04"),
+		formatDuration(now.Sub(*task.ActiveStartTime).Nanoseconds()),
+		lastActivity(task).Local().Format("Mon 15:04"))
+}
+
+// askStaleTimers asks what to do with each stale timer of the list when it
+// is opened in interactive mode.
+func askStaleTimers(s *session) {
+	now := time.Now()
+	for _, task := range staleTimers(s.taskList, now, s.config.staleAfter()) {
+		fmt.Printf("\n[!] %s\n", staleTimerLine(task, now))
+		taskID := task.ID
+		for {
+			fmt.Print("    k keep running | l stop at last activity | t stop at a time | d discard: ")
+			line, ok := readLine()
+			if !ok {
+				return
+			}
+
+			var action string
+			var at time.Time
+			var err error
+			switch strings.TrimSpace(strings.ToLower(line)) {
+			case "k", "keep":
+				action = recoverKeep
+			case "l", "last":
+				action = recoverLast
+			case "d", "discard":
+				action = recoverDiscard
+			case "t", "time":
+				fmt.Print("    Stop at ([day] HH:MM): ")
+				line, ok := readLine()
+				if !ok {
+					return
+				}
+				if at, err = parseStopTime(strings.Fields(line), time.Now()); err != nil {
+					fmt.Printf("[!] %v\n", err)
+					continue
+				}
+				action = recoverAt
+			default:
+				continue
+			}
+
+			others, err := loadOtherLists(s.store, s.listName)
+			if err == nil {
+				err = s.update(func(taskList *TaskList) error {
+					task, err := findAnyTask(taskList, taskID)
+					if err != nil {
+						return err
+					}
+					return recoverTimer(taskList, others, task, action, at)
+				})
+			}
+			if err != nil {
+				fmt.Printf("[!] %v\n", err)
+				continue
+			}
+			break
+		}
+	}
+}
+
+// checkStaleTimers is for one-shot commands that may stop timers: it fails
+// while the list has a stale timer, stopping it would record a session of
+// many hours nobody worked. It has to be resolved with 'tgo recover' first.
+func checkStaleTimers(config *Config, taskList *TaskList) error {
+	for _, task := range staleTimers(taskList, time.Now(), config.staleAfter()) {
+		return fmt.Errorf("'%s' has been running since %s, run 'tgo recover %s' first",
+			task.Title, task.ActiveStartTime.Local().Format("Mon 2006-01-02 15:04"), anyTaskRef(taskList, task))
+	}
+	return nil
+}
+
+// stopsTimers adds checkStaleTimers to op.
+func stopsTimers(config *Config, op func(taskList *TaskList, index int) error) func(*TaskList, int) error {
+	return func(taskList *TaskList, index int) error {
+		if err := checkStaleTimers(config, taskList); err != nil {
+			return err
+		}
+		return op(taskList, index)
+	}
+}
+
+// staleWarnings tells one-shot commands about stale timers, they don't ask.
+func staleWarnings(config *Config, taskList *TaskList) []string {
+	now := time.Now()
+	var warnings []string
+	for _, task := range staleTimers(taskList, now, config.staleAfter()) {
+		warnings = append(warnings, fmt.Sprintf("[!] %s, run 'tgo recover %s'", staleTimerLine(task, now), anyTaskRef(taskList, task)))
+	}
+	return warnings
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IsClosed reports whether the task needs no more work, because it's done or
+// was cancelled.
+func (t *Task) IsClosed() bool {
+	return t.Status == StatusDone || t.Status == StatusCancelled
+}
+
+func (t *Task) IsWaiting() bool {
+	return t.Status == StatusWaiting
+}
+
+// FollowUpDue reports whether it's time to check on a waiting task.
+func (t *Task) FollowUpDue(now time.Time) bool {
+	return t.IsWaiting() && t.FollowUpAt != nil && !now.Before(dueDeadline(t.FollowUpAt.In(now.Location())))
+}
+
+// openStatus is the status a task goes back to when it's no longer done,
+// cancelled or waiting: paused if time was tracked on it, else pending.
+func (t *Task) openStatus() TaskStatus {
+	if t.TotalDuration > 0 || len(t.Sessions) > 0 {
+		return StatusPaused
+	}
+	return StatusPending
+}
+
+// setStatus changes the task's status and records the change in its history.
+func (t *Task) setStatus(status TaskStatus, now time.Time) {
+	if t.Status == status {
+		return
+	}
+	t.History = append(t.History, StatusChange{At: now, From: t.Status, To: status})
+	t.Status = status
+	t.touch(now)
+}
+
+// touch records a timer or status action on the task, it shows the user was
+// still around while a timer ran.
+func (t *Task) touch(now time.Time) {
+	t.LastActivity = &now
+}
+
+func (t *Task) clearWaiting() {
+	t.WaitingOn = ""
+	t.FollowUpAt = nil
+}
+
+// cancelTask marks a task as not going to be done, or takes it back if it
+// already was cancelled. Cancelling an instance of a recurring task skips
+// it, the next one is still added.
+func cancelTask(taskList *TaskList, index int) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	now := time.Now()
+	if task.Status == StatusCancelled {
+		task.setStatus(task.openStatus(), now)
+		fmt.Printf("[ ] Restored: %s\n", task.Title)
+		takeBackNextInstance(taskList, task.ID)
+		return nil
+	}
+	if task.IsDone() {
+		return fmt.Errorf("cannot cancel completed task")
+	}
+
+	stopTaskTimer(task, now)
+	for i := range task.Children {
+		if task.Children[i].IsActive() {
+			stopTaskTimer(&task.Children[i], now)
+		}
+	}
+	task.clearWaiting()
+	task.setStatus(StatusCancelled, now)
+	fmt.Printf("[/] Cancelled: %s\n", task.Title)
+
+	if task.Recur != "" {
+		spawnNextInstance(taskList, task, now)
+	}
+	return nil
+}
+
+// parseWaitArgs splits "<note> until <when>" into the waiting-on note and
+// the follow-up date. Both are optional.
+func parseWaitArgs(s string) (string, *time.Time, error) {
+	padded := " " + strings.TrimSpace(s) + " "
+	i := strings.LastIndex(padded, " until ")
+	if i < 0 {
+		return strings.TrimSpace(s), nil, nil
+	}
+
+	followUp, err := parseDue(padded[i+len(" until "):], time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimSpace(padded[:i]), &followUp, nil
+}
+
+// waitTask puts a task on hold until someone else delivers, or updates what
+// a waiting task waits for.
+func waitTask(taskList *TaskList, index int, note string, followUp *time.Time) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	if task.IsClosed() {
+		return fmt.Errorf("cannot wait on a %s task", task.Status)
+	}
+
+	now := time.Now()
+	stopTaskTimer(task, now)
+	for i := range task.Children {
+		if task.Children[i].IsActive() {
+			stopTaskTimer(&task.Children[i], now)
+		}
+	}
+
+	task.setStatus(StatusWaiting, now)
+	task.WaitingOn = note
+	task.FollowUpAt = followUp
+
+	info := ""
+	if note != "" {
+		info += " on " + note
+	}
+	if followUp != nil {
+		info += ", follow up " + formatDue(*followUp, now)
+	}
+	fmt.Printf("[~] Waiting%s: %s\n", info, task.Title)
+	return nil
+}
+
+// stopWaiting takes a waiting task off hold.
+func stopWaiting(taskList *TaskList, index int) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	if !task.IsWaiting() {
+		return fmt.Errorf("'%s' isn't waiting", task.Title)
+	}
+	task.clearWaiting()
+	task.setStatus(task.openStatus(), time.Now())
+	fmt.Printf("[ ] No longer waiting: %s\n", task.Title)
+	return nil
+}
+
+// reopenTask puts a done, cancelled or waiting task back on the open list.
+// A recurring task takes back the instance its completion added, see
+// takeBackNextInstance.
+func reopenTask(taskList *TaskList, index int) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	if task.IsWaiting() {
+		return stopWaiting(taskList, index)
+	}
+	if !task.IsClosed() {
+		return fmt.Errorf("'%s' is already open", task.Title)
+	}
+
+	task.setStatus(task.openStatus(), time.Now())
+	task.CompletedAt = nil
+	fmt.Printf("[ ] Reopened: %s\n", task.Title)
+	takeBackNextInstance(taskList, task.ID)
+	return nil
+}
+
+// getHistoryLines renders the status changes of a task, oldest first.
+func getHistoryLines(task *Task) []string {
+	lines := []string{fmt.Sprintf("[i] %s (%s)", task.Title, task.ShortID())}
+	if len(task.History) == 0 {
+		return append(lines, "    No status changes recorded")
+	}
+	for _, change := range task.History {
+		lines = append(lines, fmt.Sprintf("    %s  %s -> %s",
+			change.At.Local().Format("2006-01-02 15:04"), change.From, change.To))
+	}
+	return lines
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	storeJSON   = "json"
+	storeSQLite = "sqlite"
+)
+
+// Store persists task lists. Lists are addressed by name, which is derived
+// from the list title when it is created.
+type Store interface {
+	// Lists returns the names of all lists, sorted.
+	Lists() ([]string, error)
+	Load(name string) (*TaskList, error)
+	// Save writes taskList. It fails with an error wrapping errListChanged if
+	// the stored list changed since taskList was loaded.
+	Save(name string, taskList *TaskList) error
+	// SaveAll saves several lists as one write: if tgo is interrupted, either
+	// none of them changes or the write is completed the next time the store
+	// is used.
+	SaveAll(names []string, taskLists []*TaskList) error
+	// Create adds taskList as a new list called name. It fails if the list
+	// already exists.
+	Create(name string, taskList *TaskList) error
+	Delete(name string) error
+	// Lock keeps other tgo processes from modifying the list until the
+	// returned func is called.
+	Lock(name string) (func(), error)
+	// Changed reports whether the stored list differs from the version
+	// taskList was loaded from.
+	Changed(name string, taskList *TaskList) (bool, error)
+}
+
+func openStore(config *Config) (Store, error) {
+	if config.TaskDir == "" {
+		return nil, fmt.Errorf("no task directory configured")
+	}
+
+	switch config.Store {
+	case "", storeJSON:
+		return newJSONStore(config.TaskDir), nil
+	case storeSQLite:
+		return openSQLiteStore(filepath.Join(config.TaskDir, sqliteFileName))
+	default:
+		return nil, fmt.Errorf("unknown store '%s', use %s or %s", config.Store, storeJSON, storeSQLite)
+	}
+}
+
+// createList adds an empty list called title and returns its name.
+func createList(store Store, title string) (string, error) {
+	name, err := listNameFromTitle(title)
+	if err != nil {
+		return "", err
+	}
+	return name, store.Create(name, newTaskList(title))
+}
+
+// importLists copies the lists of from into to under the same names. Lists
+// to already has are left alone, so importing twice doesn't duplicate
+// anything.
+func importLists(from, to Store) (int, error) {
+	names, err := from.Lists()
+	if err != nil {
+		return 0, err
+	}
+	existing, err := to.Lists()
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, name := range names {
+		if slices.Contains(existing, name) {
+			fmt.Printf("[i] Skipped %s, it already exists\n", name)
+			continue
+		}
+		taskList, err := from.Load(name)
+		if err != nil {
+			return imported, fmt.Errorf("load error: %s: %v", name, err)
+		}
+		copied := *taskList
+		copied.source = nil
+		copied.revision = 0
+		if err := to.Create(name, &copied); err != nil {
+			return imported, fmt.Errorf("%s: %v", name, err)
+		}
+		fmt.Printf("[+] Imported %s (%d tasks)\n", name, len(copied.Items))
+		imported++
+	}
+	return imported, nil
+}
+
+// updateTasks applies op to taskList and saves the result while holding the
+// list's lock. If the list was changed by someone else since it was loaded,
+// it is reloaded first and op runs against the current contents, so those
+// changes aren't clobbered.
+func updateTasks(store Store, listName string, taskList *TaskList, op func(*TaskList) error) error {
+	unlock, err := store.Lock(listName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	changed, err := store.Changed(listName, taskList)
+	if err != nil {
+		return err
+	}
+	if changed {
+		fresh, err := store.Load(listName)
+		if err != nil {
+			return err
+		}
+		*taskList = *fresh
+		fmt.Fprintf(os.Stderr, "[i] %s changed on disk, reloaded\n", listName)
+	}
+
+	if err := op(taskList); err != nil {
+		return err
+	}
+	return store.Save(listName, taskList)
+}
+
+// updateLists is updateTasks for an operation that changes several lists,
+// e.g. moving a task from one list to another. The lists are saved together
+// with SaveAll.
+func updateLists(store Store, names []string, taskLists []*TaskList, op func() error) error {
+	unlock, err := lockLists(store, names)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return updateLockedLists(store, names, taskLists, op)
+}
+
+// lockLists locks the lists in sorted order, so two commands locking some of
+// the same lists can't deadlock.
+func lockLists(store Store, names []string) (func(), error) {
+	locked := slices.Clone(names)
+	slices.Sort(locked)
+	var unlocks []func()
+	unlockAll := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, name := range slices.Compact(locked) {
+		unlock, err := store.Lock(name)
+		if err != nil {
+			unlockAll()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlock)
+	}
+	return unlockAll, nil
+}
+
+// updateLockedLists is updateLists for lists the caller already locked.
+func updateLockedLists(store Store, names []string, taskLists []*TaskList, op func() error) error {
+	for i, name := range names {
+		changed, err := store.Changed(name, taskLists[i])
+		if err != nil {
+			return err
+		}
+		if changed {
+			fresh, err := store.Load(name)
+			if err != nil {
+				return err
+			}
+			*taskLists[i] = *fresh
+			fmt.Fprintf(os.Stderr, "[i] %s changed on disk, reloaded\n", name)
+		}
+	}
+
+	if err := op(); err != nil {
+		return err
+	}
+	return store.SaveAll(names, taskLists)
+}
+
+// transferTask moves the task with taskID from the list fromName to the list
+// called toList. Both lists are saved together, so the task is never lost or
+// duplicated.
+func transferTask(store Store, fromName string, from *TaskList, taskID int64, toList string) error {
+	listNames, err := store.Lists()
+	if err != nil {
+		return err
+	}
+	toName, err := findList(listNames, toList)
+	if err != nil {
+		return err
+	}
+	if toName == fromName {
+		return fmt.Errorf("task is already in %s", toName)
+	}
+
+	to, err := store.Load(toName)
+	if err != nil {
+		return err
+	}
+
+	return updateLists(store, []string{fromName, toName}, []*TaskList{from, to}, func() error {
+		index, err := findTaskIndex(from, taskID)
+		if err != nil {
+			return err
+		}
+		return moveTaskToList(from, index, to, toName)
+	})
+}
+
+// resolveList picks the list a one-shot command works on: the requested
+// list (--list or $TGO_LIST), the configured default list, the last used list
+// or the only list there is. It never prompts, anything else is an error.
+func resolveList(config *Config, store Store, requested string) (string, error) {
+	listNames, err := store.Lists()
+	if err != nil {
+		return "", err
+	}
+	if len(listNames) == 0 {
+		return "", fmt.Errorf("no task lists found, create one with 'tgo create-list <name>'")
+	}
+
+	if requested != "" {
+		return findList(listNames, requested)
+	}
+	if config.DefaultList != "" {
+		name, err := findList(listNames, config.DefaultList)
+		if err != nil {
+			return "", fmt.Errorf("default list: %v", err)
+		}
+		return name, nil
+	}
+	if config.LastList != "" {
+		if name, err := findList(listNames, config.LastList); err == nil {
+			return name, nil
+		}
+	}
+	if len(listNames) == 1 {
+		return listNames[0], nil
+	}
+	return "", fmt.Errorf("no list given and there are %d lists, use --list <name>", len(listNames))
+}
+
+// findList returns the list called name. name may also be the list's title
+// or an unambiguous prefix of its name.
+func findList(listNames []string, name string) (string, error) {
+	wanted, err := listNameFromTitle(name)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, listName := range listNames {
+		if listName == name || listName == wanted {
+			return listName, nil
+		}
+		if strings.HasPrefix(listName, wanted) {
+			matches = append(matches, listName)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("list '%s' not found", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("list '%s' is ambiguous: %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// listNameFromTitle turns a list title into the name it's stored under.
+func listNameFromTitle(title string) (string, error) {
+	if strings.TrimSpace(title) == "" {
+		return "", fmt.Errorf("list name cannot be empty")
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if r == ' ' {
+			return '-'
+		}
+		if r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') ||
+			(r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, strings.ToLower(title))
+
+	if name == "" {
+		return "", fmt.Errorf("list name '%s' has no usable characters", title)
+	}
+	return name, nil
+}
+
+func newTaskList(title string) *TaskList {
+	now := time.Now()
+	return &TaskList{
+		SchemaVersion: currentSchemaVersion,
+		Title:         title,
+		Items:         []Task{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// prepareSave stamps the list before any store writes it.
+func (taskList *TaskList) prepareSave() {
+	taskList.UpdatedAt = time.Now()
+	if taskList.SchemaVersion < currentSchemaVersion {
+		taskList.SchemaVersion = currentSchemaVersion
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errListChanged = errors.New("was changed on disk by another program, reload and try again")
+
+// jsonStore keeps every list in its own .json file in dir. It is the default
+// store and the one meant for syncing with NextCloud and the like.
+type jsonStore struct {
+	dir string
+}
+
+func newJSONStore(dir string) *jsonStore {
+	return &jsonStore{dir: dir}
+}
+
+func (s *jsonStore) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+func (s *jsonStore) Lists() ([]string, error) {
+	if err := s.recoverJournal(); err != nil {
+		return nil, err
+	}
+
+	taskFiles, err := findTaskFiles(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(taskFiles))
+	for i, file := range taskFiles {
+		names[i] = strings.TrimSuffix(file, ".json")
+	}
+	return names, nil
+}
+
+func (s *jsonStore) Load(name string) (*TaskList, error) {
+	if err := s.recoverJournal(); err != nil {
+		return nil, err
+	}
+
+	taskList, err := loadTasks(s.path(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("list '%s' not found", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.rememberLoad(name, taskList)
+	return taskList, nil
+}
+
+func (s *jsonStore) Save(name string, taskList *TaskList) error {
+	if err := saveTasks(s.path(name), taskList); err != nil {
+		return err
+	}
+	if data, err := json.MarshalIndent(taskList, "", "  "); err == nil {
+		s.rememberSave(name, data)
+	}
+	return nil
+}
+
+// SaveAll first writes all lists to a journal file, then to their own files.
+// A journal left behind by an interrupted write is replayed by recoverJournal.
+func (s *jsonStore) SaveAll(names []string, taskLists []*TaskList) error {
+	unlock, err := lockFile(s.journalPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry := journal{Lists: make(map[string]json.RawMessage)}
+	for i, name := range names {
+		changed, err := taskLists[i].changedOnDisk(s.path(name))
+		if err != nil {
+			return err
+		}
+		if changed {
+			return fmt.Errorf("%s %w", name+".json", errListChanged)
+		}
+
+		taskLists[i].prepareSave()
+		data, err := json.MarshalIndent(taskLists[i], "", "  ")
+		if err != nil {
+			return err
+		}
+		entry.Lists[name] = data
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.journalPath(), data, 0644, false); err != nil {
+		return fmt.Errorf("cannot write journal: %v", err)
+	}
+
+	for i, name := range names {
+		filePath := s.path(name)
+		if err := writeFileAtomic(filePath, entry.Lists[name], 0644, true); err != nil {
+			return err
+		}
+		source, err := snapshotFile(filePath, entry.Lists[name])
+		if err != nil {
+			return err
+		}
+		taskLists[i].source = source
+		s.rememberSave(name, entry.Lists[name])
+	}
+
+	if err := os.Remove(s.journalPath()); err != nil {
+		return err
+	}
+	syncDir(s.dir)
+	return nil
+}
+
+// journal holds the lists of a SaveAll until all of them are written.
+type journal struct {
+	Lists map[string]json.RawMessage `json:"lists"`
+}
+
+func (s *jsonStore) journalPath() string {
+	return filepath.Join(s.dir, ".tgo-journal")
+}
+
+// recoverJournal completes a SaveAll that was interrupted, so a task that was
+// being moved is never lost or left in both lists.
+func (s *jsonStore) recoverJournal() error {
+	if _, err := os.Stat(s.journalPath()); os.IsNotExist(err) {
+		return nil
+	}
+
+	unlock, err := lockFile(s.journalPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(s.journalPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entry journal
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// The journal itself was only partly written, so none of the lists
+		// were touched yet.
+		return os.Remove(s.journalPath())
+	}
+	for name, listData := range entry.Lists {
+		if err := writeFileAtomic(s.path(name), listData, 0644, true); err != nil {
+			return err
+		}
+		s.rememberSave(name, listData)
+	}
+	fmt.Fprintf(os.Stderr, "[i] Completed an interrupted write of %d lists\n", len(entry.Lists))
+
+	if err := os.Remove(s.journalPath()); err != nil {
+		return err
+	}
+	syncDir(s.dir)
+	return nil
+}
+
+func (s *jsonStore) Create(name string, taskList *TaskList) error {
+	filePath := s.path(name)
+	unlock, err := lockFile(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(filePath); err == nil {
+		return fmt.Errorf("list '%s' already exists", name)
+	}
+
+	if err := saveTasks(filePath, taskList); err != nil {
+		return err
+	}
+	if data, err := json.MarshalIndent(taskList, "", "  "); err == nil {
+		s.rememberSave(name, data)
+	}
+	return nil
+}
+
+func (s *jsonStore) Delete(name string) error {
+	filePath := s.path(name)
+	unlock, err := lockFile(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(filePath); err != nil {
+		return err
+	}
+	s.forgetVersions(name)
+	return nil
+}
+
+func (s *jsonStore) Lock(name string) (func(), error) {
+	return lockFile(s.path(name))
+}
+
+func (s *jsonStore) Changed(name string, taskList *TaskList) (bool, error) {
+	return taskList.changedOnDisk(s.path(name))
+}
+
+func findTaskFiles(folder string) ([]string, error) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read folder %s: %v", folder, err)
+	}
+
+	var taskFiles []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if _, isConflict := conflictTarget(entry.Name()); isConflict {
+			continue
+		}
+		taskFiles = append(taskFiles, entry.Name())
+	}
+
+	return taskFiles, nil
+}
+
+func loadTasks(filePath string) (*TaskList, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := snapshotFile(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+
+	taskList, err := decodeTaskList(data)
+	if err == nil {
+		taskList.source = source
+		return taskList, nil
+	}
+
+	backup, backupErr := loadBackup(filePath)
+	if backupErr != nil {
+		return nil, fmt.Errorf("%s is corrupt (%v) and no usable backup exists", filepath.Base(filePath), err)
+	}
+	fmt.Fprintf(os.Stderr, "[!] %s is corrupt (%v), loaded last backup\n", filepath.Base(filePath), err)
+	backup.source = source
+	return backup, nil
+}
+
+func loadBackup(filePath string) (*TaskList, error) {
+	data, err := os.ReadFile(filePath + backupSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTaskList(data)
+}
+
+// decodeTaskList parses a task list document, migrating it from older schema
+// versions first.
+func decodeTaskList(data []byte) (*TaskList, error) {
+	data, err := migrateTaskList(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var taskList TaskList
+	if err := json.Unmarshal(data, &taskList); err != nil {
+		return nil, err
+	}
+	return &taskList, nil
+}
+
+// saveTasks writes taskList to filePath. It refuses to overwrite the file if
+// it changed since taskList was loaded, returning an error wrapping
+// errListChanged.
+func saveTasks(filePath string, taskList *TaskList) error {
+	changed, err := taskList.changedOnDisk(filePath)
+	if err != nil {
+		return err
+	}
+	if changed {
+		return fmt.Errorf("%s %w", filepath.Base(filePath), errListChanged)
+	}
+
+	taskList.prepareSave()
+	data, err := json.MarshalIndent(taskList, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filePath, data, 0644, true); err != nil {
+		return err
+	}
+
+	source, err := snapshotFile(filePath, data)
+	if err != nil {
+		return err
+	}
+	taskList.source = source
+	return nil
+}
+
+// changedOnDisk reports whether filePath differs from the version taskList
+// was loaded from. Lists that were never loaded are never stale.
+func (taskList *TaskList) changedOnDisk(filePath string) (bool, error) {
+	if taskList.source == nil {
+		return false, nil
+	}
+	return taskList.source.changed(filePath)
+}
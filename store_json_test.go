@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecoverJournal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	store := newJSONStore(dir)
+
+	// A task moved from work to home: the journal was written, but only
+	// home.json was before the write was interrupted.
+	oldWork := `{"schema_version":1,"title":"Work","items":[{"id":1,"title":"A","status":"pending"}]}`
+	newWork := `{"schema_version":1,"title":"Work","items":[]}`
+	newHome := `{"schema_version":1,"title":"Home","items":[{"id":1,"title":"A","status":"pending"}]}`
+	writeFile(t, filepath.Join(dir, "work.json"), oldWork)
+	writeFile(t, filepath.Join(dir, "home.json"), newHome)
+	entry, err := json.Marshal(journal{Lists: map[string]json.RawMessage{
+		"work": json.RawMessage(newWork),
+		"home": json.RawMessage(newHome),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, store.journalPath(), string(entry))
+
+	work, err := store.Load("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(work.Items) != 0 {
+		t.Errorf("work has %d tasks, want the moved task gone", len(work.Items))
+	}
+	home, err := store.Load("home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(home.Items) != 1 {
+		t.Errorf("home has %d tasks, want the moved task", len(home.Items))
+	}
+	if _, err := os.Stat(store.journalPath()); !os.IsNotExist(err) {
+		t.Errorf("journal still exists after recovery: %v", err)
+	}
+}
+
+func TestRecoverJournalPartlyWritten(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	store := newJSONStore(dir)
+
+	work := `{"schema_version":1,"title":"Work","items":[{"id":1,"title":"A","status":"pending"}]}`
+	writeFile(t, filepath.Join(dir, "work.json"), work)
+	writeFile(t, store.journalPath(), `{"lists":{"work":{"title"`)
+
+	names, err := store.Lists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "work" {
+		t.Errorf("lists = %q, want [work]", names)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "work.json")); string(data) != work {
+		t.Errorf("work.json changed to %s", data)
+	}
+	if _, err := os.Stat(store.journalPath()); !os.IsNotExist(err) {
+		t.Errorf("journal still exists: %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteFileName = "tgo.db"
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS lists (
+	name     TEXT PRIMARY KEY,
+	revision INTEGER NOT NULL,
+	data     TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS tasks (
+	list     TEXT NOT NULL REFERENCES lists(name) ON DELETE CASCADE,
+	id       INTEGER NOT NULL,
+	position INTEGER NOT NULL,
+	data     TEXT NOT NULL,
+	PRIMARY KEY (list, id)
+);
+`
+
+// sqliteStore keeps all lists in one SQLite database. Tasks are stored one
+// row each, so saving a list only rewrites the tasks that changed. Lists and
+// tasks are kept as JSON documents, the same format the JSON store writes,
+// so migrations and unknown fields work the same way.
+type sqliteStore struct {
+	path string
+	db   *sql.DB
+}
+
+func openSQLiteStore(path string) (*sqliteStore, error) {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() +
+		"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot open %s: %v", filepath.Base(path), err)
+	}
+	return &sqliteStore{path: path, db: db}, nil
+}
+
+func (s *sqliteStore) Lists() ([]string, error) {
+	rows, err := s.db.Query(`SELECT name FROM lists ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func (s *sqliteStore) Load(name string) (*TaskList, error) {
+	var revision int64
+	var listData []byte
+	err := s.db.QueryRow(`SELECT revision, data FROM lists WHERE name = ?`, name).Scan(&revision, &listData)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("list '%s' not found", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT data FROM tasks WHERE list = ? ORDER BY position`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []json.RawMessage
+	for rows.Next() {
+		var taskData []byte
+		if err := rows.Scan(&taskData); err != nil {
+			return nil, err
+		}
+		items = append(items, json.RawMessage(taskData))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Reassemble the list document so it goes through the same migrations
+	// as a JSON file.
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(listData, &doc); err != nil {
+		return nil, fmt.Errorf("list '%s' is corrupt: %v", name, err)
+	}
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+	if doc["items"], err = json.Marshal(items); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	taskList, err := decodeTaskList(data)
+	if err != nil {
+		return nil, fmt.Errorf("list '%s' is corrupt: %v", name, err)
+	}
+	taskList.revision = revision
+	return taskList, nil
+}
+
+func (s *sqliteStore) Save(name string, taskList *TaskList) error {
+	return s.SaveAll([]string{name}, []*TaskList{taskList})
+}
+
+// SaveAll saves the lists in one transaction.
+func (s *sqliteStore) SaveAll(names []string, taskLists []*TaskList) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, name := range names {
+		if err := saveSQLiteList(tx, name, taskLists[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, taskList := range taskLists {
+		taskList.revision++
+	}
+	return nil
+}
+
+func saveSQLiteList(tx *sql.Tx, name string, taskList *TaskList) error {
+	var revision int64
+	err := tx.QueryRow(`SELECT revision FROM lists WHERE name = ?`, name).Scan(&revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("list '%s' not found", name)
+	}
+	if err != nil {
+		return err
+	}
+	if revision != taskList.revision {
+		return fmt.Errorf("%s %w", name, errListChanged)
+	}
+
+	taskList.prepareSave()
+	listData, err := encodeListHeader(taskList)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE lists SET revision = ?, data = ? WHERE name = ?`, revision+1, listData, name); err != nil {
+		return err
+	}
+
+	return saveSQLiteTasks(tx, name, taskList.Items)
+}
+
+type storedTask struct {
+	position int
+	data     []byte
+}
+
+// saveSQLiteTasks writes only the tasks that were added, changed or moved and
+// deletes the ones that are gone.
+func saveSQLiteTasks(tx *sql.Tx, name string, items []Task) error {
+	rows, err := tx.Query(`SELECT id, position, data FROM tasks WHERE list = ?`, name)
+	if err != nil {
+		return err
+	}
+	stored := make(map[int64]storedTask)
+	for rows.Next() {
+		var id int64
+		var task storedTask
+		if err := rows.Scan(&id, &task.position, &task.data); err != nil {
+			rows.Close()
+			return err
+		}
+		stored[id] = task
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range items {
+		data, err := json.Marshal(&items[i])
+		if err != nil {
+			return err
+		}
+		id := items[i].ID
+		old, ok := stored[id]
+		delete(stored, id)
+		if ok && old.position == i && bytes.Equal(old.data, data) {
+			continue
+		}
+		_, err = tx.Exec(`INSERT INTO tasks (list, id, position, data) VALUES (?, ?, ?, ?)
+			ON CONFLICT (list, id) DO UPDATE SET position = excluded.position, data = excluded.data`,
+			name, id, i, data)
+		if err != nil {
+			return fmt.Errorf("cannot save task '%s': %v", items[i].Title, err)
+		}
+	}
+
+	for id := range stored {
+		if _, err := tx.Exec(`DELETE FROM tasks WHERE list = ? AND id = ?`, name, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeListHeader returns the list document without its items.
+func encodeListHeader(taskList *TaskList) ([]byte, error) {
+	header := *taskList
+	header.Items = nil
+	data, err := json.Marshal(&header)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	delete(doc, "items")
+	return json.Marshal(doc)
+}
+
+func (s *sqliteStore) Create(name string, taskList *TaskList) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Add the list at revision 0, saving it brings it to 1.
+	result, err := tx.Exec(`INSERT INTO lists (name, revision, data) VALUES (?, 0, '{}') ON CONFLICT DO NOTHING`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("list '%s' already exists", name)
+	}
+	taskList.revision = 0
+	if err := saveSQLiteList(tx, name, taskList); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	taskList.revision++
+	return nil
+}
+
+// Close closes the database.
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqliteStore) Delete(name string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM tasks WHERE list = ?`, name); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM lists WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("list '%s' not found", name)
+	}
+	return tx.Commit()
+}
+
+// Lock uses the same lock files as the JSON store, so a read-modify-write
+// waits for other tgo processes instead of failing on the revision check.
+func (s *sqliteStore) Lock(name string) (func(), error) {
+	return lockFile(s.path + "." + name)
+}
+
+func (s *sqliteStore) Changed(name string, taskList *TaskList) (bool, error) {
+	var revision int64
+	err := s.db.QueryRow(`SELECT revision FROM lists WHERE name = ?`, name).Scan(&revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return revision != taskList.revision, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subtasks are tasks in a task's Children. They only go one level deep and
+// are addressed as <task>.<n>, e.g. "3.2" or "k3f9.2".
+
+// progress returns how many of the task's subtasks are done.
+func (t *Task) progress() (int, int) {
+	done := 0
+	for i := range t.Children {
+		if t.Children[i].IsDone() {
+			done++
+		}
+	}
+	return done, len(t.Children)
+}
+
+// hasActiveChild reports whether a timer runs on one of the task's subtasks.
+func (t *Task) hasActiveChild() bool {
+	for i := range t.Children {
+		if t.Children[i].IsActive() {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSubtaskRef splits a "<task>.<n>" reference into the 1-based index
+// of the task and of its subtask.
+func resolveSubtaskRef(taskList *TaskList, ref string) (int, int, error) {
+	taskRef, childRef, ok := strings.Cut(strings.TrimSpace(ref), ".")
+	if !ok {
+		return 0, 0, fmt.Errorf("'%s' is not a subtask, use <task>.<n>, e.g. 3.2", ref)
+	}
+	index, err := resolveTaskRef(taskList, taskRef)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	children := taskList.Items[index-1].Children
+	child, err := strconv.Atoi(childRef)
+	if err != nil || child < 1 || child > len(children) {
+		if len(children) == 0 {
+			return 0, 0, fmt.Errorf("'%s' has no subtasks", taskList.Items[index-1].Title)
+		}
+		return 0, 0, fmt.Errorf("invalid subtask number. Use %s.1-%d", taskRef, len(children))
+	}
+	return index, child, nil
+}
+
+// resolveSubtaskID returns the IDs of the task and subtask ref points at.
+func resolveSubtaskID(taskList *TaskList, ref string) (int64, int64, error) {
+	index, child, err := resolveSubtaskRef(taskList, ref)
+	if err != nil {
+		return 0, 0, err
+	}
+	task := &taskList.Items[index-1]
+	return task.ID, task.Children[child-1].ID, nil
+}
+
+// findSubtaskIndex returns the 1-based indices of a task and its subtask by
+// their IDs.
+func findSubtaskIndex(taskList *TaskList, taskID, childID int64) (int, int, error) {
+	index, err := findTaskIndex(taskList, taskID)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := range taskList.Items[index-1].Children {
+		if taskList.Items[index-1].Children[i].ID == childID {
+			return index, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("subtask no longer exists")
+}
+
+// resolveAnyTask returns the task ref points at, or its subtask for a
+// <task>.<n> ref.
+func resolveAnyTask(taskList *TaskList, ref string) (*Task, error) {
+	if strings.Contains(ref, ".") {
+		index, child, err := resolveSubtaskRef(taskList, ref)
+		if err != nil {
+			return nil, err
+		}
+		return &taskList.Items[index-1].Children[child-1], nil
+	}
+	index, err := resolveTaskRef(taskList, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &taskList.Items[index-1], nil
+}
+
+// anyTaskRef returns the short ID of a task, or <short ID>.<n> for a subtask.
+func anyTaskRef(taskList *TaskList, task *Task) string {
+	for i := range taskList.Items {
+		for j := range taskList.Items[i].Children {
+			if taskList.Items[i].Children[j].ID == task.ID {
+				return fmt.Sprintf("%s.%d", taskList.Items[i].ShortID(), j+1)
+			}
+		}
+	}
+	return task.ShortID()
+}
+
+// findAnyTask finds a task or subtask by its ID.
+func findAnyTask(taskList *TaskList, id int64) (*Task, error) {
+	for i := range taskList.Items {
+		task := &taskList.Items[i]
+		if task.ID == id {
+			return task, nil
+		}
+		for j := range task.Children {
+			if task.Children[j].ID == id {
+				return &task.Children[j], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("task no longer exists")
+}
+
+func addSubtask(taskList *TaskList, index int, title string) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return fmt.Errorf("subtask title cannot be empty")
+	}
+
+	task := &taskList.Items[index-1]
+	task.Children = append(task.Children, Task{
+		ID:        newTaskID(taskList),
+		Title:     title,
+		Status:    StatusPending,
+		Sessions:  []Session{},
+		CreatedAt: time.Now(),
+	})
+	fmt.Printf("[+] Added to %s: %s\n", task.Title, title)
+	return nil
+}
+
+func removeSubtask(taskList *TaskList, index, child int) error {
+	task := &taskList.Items[index-1]
+	removed := task.Children[child-1]
+	task.Children = slices.Delete(task.Children, child-1, child)
+	if len(task.Children) == 0 {
+		task.Children = nil
+	}
+	fmt.Printf("[-] Removed from %s: %s\n", task.Title, removed.Title)
+	return nil
+}
+
+// toggleSubtaskDone checks a subtask off, or reopens it if it was done.
+func toggleSubtaskDone(taskList *TaskList, index, child int) error {
+	task := &taskList.Items[index-1]
+	subtask := &task.Children[child-1]
+	now := time.Now()
+	task.touch(now)
+
+	if subtask.IsDone() {
+		subtask.setStatus(subtask.openStatus(), now)
+		subtask.CompletedAt = nil
+		fmt.Printf("[ ] Reopened: %s\n", subtask.Title)
+	} else {
+		stopTaskTimer(subtask, now)
+		subtask.setStatus(StatusDone, now)
+		subtask.CompletedAt = &now
+		fmt.Printf("[x] Checked: %s\n", subtask.Title)
+	}
+
+	done, total := task.progress()
+	fmt.Printf("[i] %s (%d/%d)\n", task.Title, done, total)
+	return nil
+}
+
+// toggleSubtaskTimer starts or stops the timer of a subtask. Its time counts
+// towards the parent task's total.
+func toggleSubtaskTimer(taskList *TaskList, index, child int) error {
+	task := &taskList.Items[index-1]
+	subtask := &task.Children[child-1]
+	if task.IsClosed() || subtask.IsDone() {
+		return fmt.Errorf("cannot start timer for completed task")
+	}
+
+	if blocking := blockers(taskList, task); len(blocking) > 0 && !subtask.IsActive() {
+		return fmt.Errorf("'%s' is blocked by %s, finish that first", task.Title, formatBlockers(blocking))
+	}
+
+	now := time.Now()
+	task.touch(now)
+	if subtask.IsActive() {
+		stopTaskTimer(subtask, now)
+		fmt.Printf("[|] Paused: %s [Session: %s] [Total: %s]\n",
+			subtask.Title,
+			formatDuration(subtask.Sessions[len(subtask.Sessions)-1].Duration),
+			subtask.GetFormattedDuration())
+		return nil
+	}
+
+	stopAllTimers(taskList, now)
+	subtask.setStatus(StatusActive, now)
+	subtask.ActiveStartTime = &now
+	fmt.Printf("[>] Started: %s > %s\n", task.Title, subtask.Title)
+	return nil
+}
+
+// stopAllTimers stops every running timer in the list, subtasks included.
+func stopAllTimers(taskList *TaskList, now time.Time) {
+	for i := range taskList.Items {
+		task := &taskList.Items[i]
+		if task.IsActive() {
+			stopTaskTimer(task, now)
+		}
+		for j := range task.Children {
+			if task.Children[j].IsActive() {
+				stopTaskTimer(&task.Children[j], now)
+			}
+		}
+	}
+}
+
+// getSubtaskLines renders the subtasks below their parent task.
+func getSubtaskLines(task *Task, number int, spinner string, now time.Time) []string {
+	var lines []string
+	for j := range task.Children {
+		child := &task.Children[j]
+
+		var statusIcon, timeInfo string
+		switch child.Status {
+		case StatusActive:
+			statusIcon = spinner
+			timeInfo = fmt.Sprintf(" [Running: %s]", formatDuration(child.TrackedDuration(now)))
+		case StatusDone:
+			statusIcon = "[x]"
+		case StatusPaused:
+			statusIcon = "[-]"
+		default:
+			statusIcon = "[ ]"
+		}
+		if child.Status != StatusActive && child.TotalDuration > 0 {
+			timeInfo = fmt.Sprintf(" [%s]", child.GetFormattedDuration())
+		}
+
+		lines = append(lines, fmt.Sprintf("     %d.%d %s %s%s", number, j+1, statusIcon, child.Title, timeInfo))
+	}
+	return lines
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// parseTags removes the +tag words from title and returns them separately,
+// so "Fix login +bug +acme" becomes "Fix login" with tags bug and acme.
+func parseTags(title string) (string, []string) {
+	var words []string
+	var tags []string
+	for _, word := range strings.Fields(title) {
+		if tag, ok := parseTag(word); ok {
+			tags = appendTag(tags, tag)
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), tags
+}
+
+// parseTag reports whether word is a +tag and returns the tag without its
+// '+'. Tags start with a letter so "+3" stays part of the title.
+func parseTag(word string) (string, bool) {
+	if !strings.HasPrefix(word, "+") {
+		return "", false
+	}
+	return normalizeTag(word[1:])
+}
+
+// normalizeTag lowercases tag and checks it only uses letters, digits and
+// - _ : . /
+func normalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(tag)
+	for i, r := range tag {
+		if i == 0 && !unicode.IsLetter(r) {
+			return "", false
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_:./", r) {
+			return "", false
+		}
+	}
+	return tag, tag != ""
+}
+
+// parseTagArgs turns tag command arguments, with or without '+', into tags.
+func parseTagArgs(args []string) ([]string, error) {
+	var tags []string
+	for _, arg := range args {
+		tag, ok := normalizeTag(strings.TrimPrefix(arg, "+"))
+		if !ok {
+			return nil, fmt.Errorf("invalid tag '%s', tags start with a letter and use letters, digits and - _ : . /", arg)
+		}
+		tags = appendTag(tags, tag)
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("at least one tag required")
+	}
+	return tags, nil
+}
+
+func appendTag(tags []string, tag string) []string {
+	if slices.Contains(tags, tag) {
+		return tags
+	}
+	return append(tags, tag)
+}
+
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "+" + strings.Join(tags, " +")
+}
+
+func (t *Task) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, tag)
+}
+
+func tagTask(taskList *TaskList, index int, tags []string) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	for _, tag := range tags {
+		task.Tags = appendTag(task.Tags, tag)
+	}
+	fmt.Printf("[+] Tagged: %s %s\n", task.Title, formatTags(task.Tags))
+	return nil
+}
+
+func untagTask(taskList *TaskList, index int, tags []string) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	task.Tags = slices.DeleteFunc(task.Tags, func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+	if len(task.Tags) == 0 {
+		task.Tags = nil
+	}
+	fmt.Printf("[-] Untagged: %s %s\n", task.Title, formatTags(task.Tags))
+	return nil
+}
+
+// taskFilter limits which tasks are shown. A nil filter shows everything.
+type taskFilter struct {
+	tags     []string
+	statuses []TaskStatus
+}
+
+var filterStatuses = []TaskStatus{
+	StatusPending, StatusActive, StatusPaused, StatusDone, StatusCancelled, StatusWaiting,
+}
+
+// parseFilter builds a filter from words like "+bug +acme waiting". Tasks
+// have to carry all of the tags and have one of the statuses to be shown.
+func parseFilter(args []string) (*taskFilter, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	filter := &taskFilter{}
+	var tagArgs []string
+	for _, arg := range args {
+		if status := TaskStatus(strings.ToLower(arg)); slices.Contains(filterStatuses, status) {
+			filter.statuses = append(filter.statuses, status)
+		} else {
+			tagArgs = append(tagArgs, arg)
+		}
+	}
+	if len(tagArgs) > 0 {
+		tags, err := parseTagArgs(tagArgs)
+		if err != nil {
+			return nil, err
+		}
+		filter.tags = tags
+	}
+	return filter, nil
+}
+
+func (f *taskFilter) matches(task *Task) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.statuses) > 0 && !slices.Contains(f.statuses, task.Status) {
+		return false
+	}
+	for _, tag := range f.tags {
+		if !task.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *taskFilter) String() string {
+	if f == nil {
+		return ""
+	}
+	words := make([]string, 0, len(f.statuses)+1)
+	for _, status := range f.statuses {
+		words = append(words, string(status))
+	}
+	if len(f.tags) > 0 {
+		words = append(words, formatTags(f.tags))
+	}
+	return strings.Join(words, " ")
+}
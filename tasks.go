@@ -1,183 +1,152 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func findTaskFiles(folder string) ([]string, error) {
-	if folder == "" {
-		return nil, fmt.Errorf("no task folder configured")
-	}
-
-	entries, err := os.ReadDir(folder)
-	if err != nil {
-		return nil, fmt.Errorf("cannot read folder %s: %v", folder, err)
-	}
-
-	var taskFiles []string
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
-			taskFiles = append(taskFiles, entry.Name())
+func selectTaskList(store Store, listNames []string) (string, error) {
+	if js, ok := store.(*jsonStore); ok {
+		if warning := conflictWarning(js.dir); warning != "" {
+			fmt.Printf("%s\n\n", warning)
 		}
 	}
 
-	if len(taskFiles) == 0 {
-
-		return nil, fmt.Errorf("no task lists found")
+	fmt.Printf("[i] Available task lists (%d):\n\n", len(listNames))
+	for i, name := range listNames {
+		fmt.Printf("  %d. %s\n", i+1, name)
 	}
 
-	return taskFiles, nil
-}
-
-func selectTaskFile(folder string, taskFiles []string) (string, error) {
-	fmt.Printf("[i] Available task lists (%d):\n\n", len(taskFiles))
-	for i, file := range taskFiles {
-		displayName := strings.TrimSuffix(file, ".json")
-		fmt.Printf("  %d. %s\n", i+1, displayName)
-	}
-
-	scanner := bufio.NewScanner(os.Stdin)
 	for {
-		fmt.Printf("\nSelect list (1-%d), create 'c <name>', or remove 'r <number>': ", len(taskFiles))
-		if !scanner.Scan() {
+		fmt.Printf("\nSelect list (1-%d), create 'c <name>', or remove 'r <number>': ", len(listNames))
+		line, ok := readLine()
+		if !ok {
 			return "", fmt.Errorf("input error")
 		}
-		input := strings.TrimSpace(scanner.Text())
+		input := strings.TrimSpace(line)
 
 		if strings.HasPrefix(input, "c ") {
-			listName := strings.TrimSpace(input[2:])
-			if listName == "" {
+			listTitle := strings.TrimSpace(input[2:])
+			if listTitle == "" {
 				fmt.Println("[!] List name required")
 				continue
 			}
-			if err := createNewList(folder, listName); err != nil {
+			if _, err := createList(store, listTitle); err != nil {
 				fmt.Printf("[!] %v\n", err)
 				continue
 			}
-			fmt.Printf("[+] Created: %s\n", listName)
-			taskFiles, err := findTaskFiles(folder)
+			fmt.Printf("[+] Created: %s\n", listTitle)
+			names, err := store.Lists()
 			if err != nil {
 				return "", err
 			}
-			displayTaskFiles(taskFiles)
+			listNames = names
+			displayTaskLists(listNames)
 			continue
 		}
 
 		if strings.HasPrefix(input, "r ") {
 			numStr := strings.TrimSpace(input[2:])
 			choice, err := strconv.Atoi(numStr)
-			if err != nil || choice < 1 || choice > len(taskFiles) {
+			if err != nil || choice < 1 || choice > len(listNames) {
 				fmt.Println("[!] Invalid selection")
 				continue
 			}
-			selectedFile := taskFiles[choice-1]
-			fmt.Printf("Remove '%s'? (y/N): ", selectedFile)
-			if scanner.Scan() && strings.ToLower(scanner.Text()) == "y" {
-				if err := os.Remove(filepath.Join(folder, selectedFile)); err != nil {
+			selected := listNames[choice-1]
+			fmt.Printf("Remove '%s'? (y/N): ", selected)
+			if answer, ok := readLine(); ok && strings.ToLower(answer) == "y" {
+				if err := store.Delete(selected); err != nil {
 					fmt.Printf("[!] Failed to remove: %v\n", err)
 					continue
 				}
-				fmt.Printf("[-] Removed: %s\n", selectedFile)
-				taskFiles, err = findTaskFiles(folder)
+				fmt.Printf("[-] Removed: %s\n", selected)
+				listNames, err = store.Lists()
 				if err != nil {
 					return "", err
 				}
-				if len(taskFiles) == 0 {
+				if len(listNames) == 0 {
 					return "", fmt.Errorf("no task lists found")
 				}
-				displayTaskFiles(taskFiles)
+				displayTaskLists(listNames)
 			}
 			continue
 		}
 
 		choice, err := strconv.Atoi(input)
-		if err != nil || choice < 1 || choice > len(taskFiles) {
+		if err != nil || choice < 1 || choice > len(listNames) {
 			fmt.Println("[!] Invalid selection")
 			continue
 		}
-		return filepath.Join(folder, taskFiles[choice-1]), nil
+		return listNames[choice-1], nil
 	}
 }
 
-func displayTaskFiles(taskFiles []string) {
-	fmt.Printf("\n[i] Available task lists (%d):\n\n", len(taskFiles))
-	for i, file := range taskFiles {
-		displayName := strings.TrimSuffix(file, ".json")
-		fmt.Printf("  %d. %s\n", i+1, displayName)
+func displayTaskLists(listNames []string) {
+	fmt.Printf("\n[i] Available task lists (%d):\n\n", len(listNames))
+	for i, name := range listNames {
+		fmt.Printf("  %d. %s\n", i+1, name)
 	}
 }
 
-func loadTasks(filePath string) (*TaskList, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
-	}
-
-	var taskList TaskList
-	err = json.Unmarshal(data, &taskList)
-	return &taskList, err
+func displayTaskList(taskList *TaskList, listName string) {
+	displayTaskListWithSpinner(taskList, listName, 0, nil)
 }
 
-func saveTasks(filePath string, taskList *TaskList) error {
-	taskList.UpdatedAt = time.Now()
-	data, err := json.MarshalIndent(taskList, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(filePath, data, 0644)
+func displayTaskListWithSpinner(taskList *TaskList, listName string, spinnerFrame int, filter *taskFilter) {
+	lines := getTaskListLines(taskList, listName, spinnerFrame, filter)
+	footer := " <num> start/stop | add <task> | remove <num> | done <num> | r back | q quit "
+	drawFullScreen(lines, footer)
 }
 
-func createNewList(folder string, listName string) error {
-	if strings.TrimSpace(listName) == "" {
-		return fmt.Errorf("list name cannot be empty")
-	}
-
-	sanitizedName := strings.ToLower(listName)
-	sanitizedName = strings.Map(func(r rune) rune {
-		if r == ' ' {
-			return '-'
-		}
-		if r == '-' || r == '_' ||
-			(r >= 'a' && r <= 'z') ||
-			(r >= '0' && r <= '9') {
-			return r
-		}
-		return -1
-	}, sanitizedName)
-
-	fileName := fmt.Sprintf("%s.json", sanitizedName)
-	filePath := filepath.Join(folder, fileName)
-
-	if _, err := os.Stat(filePath); err == nil {
-		return fmt.Errorf("list '%s' already exists", listName)
-	}
-
-	now := time.Now()
-	newTaskList := &TaskList{
-		Title:     listName,
-		Items:     []Task{},
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
+// taskSection is a group of tasks in the list view, in display order.
+type taskSection string
+
+const (
+	sectionOverdue   taskSection = "OVERDUE"
+	sectionDueToday  taskSection = "DUE TODAY"
+	sectionActive    taskSection = "ACTIVE"
+	sectionPending   taskSection = "PENDING"
+	sectionWaiting   taskSection = "WAITING"
+	sectionBlocked   taskSection = "BLOCKED"
+	sectionDone      taskSection = "DONE"
+	sectionCancelled taskSection = "CANCELLED"
+)
 
-	return saveTasks(filePath, newTaskList)
+var taskSections = []taskSection{
+	sectionOverdue, sectionDueToday, sectionActive, sectionPending,
+	sectionWaiting, sectionBlocked, sectionDone, sectionCancelled,
 }
 
-func displayTaskList(taskList *TaskList, fileName string) {
-	displayTaskListWithSpinner(taskList, fileName, 0)
+// sectionOf returns the section a task is shown in. Running tasks always
+// stay in ACTIVE, open tasks that are due move up into their own sections
+// unless they wait for another task.
+func sectionOf(taskList *TaskList, task *Task, now time.Time) taskSection {
+	switch {
+	case task.Status == StatusActive || task.hasActiveChild():
+		return sectionActive
+	case task.Status == StatusDone:
+		return sectionDone
+	case task.Status == StatusCancelled:
+		return sectionCancelled
+	case task.Status == StatusWaiting:
+		return sectionWaiting
+	case isBlocked(taskList, task):
+		return sectionBlocked
+	case task.IsOverdue(now):
+		return sectionOverdue
+	case task.IsDueToday(now):
+		return sectionDueToday
+	default:
+		return sectionPending
+	}
 }
 
-func displayTaskListWithSpinner(taskList *TaskList, fileName string, spinnerFrame int) {
-	listName := strings.TrimSuffix(fileName, ".json")
-
+func getTaskListLines(taskList *TaskList, listName string, spinnerFrame int, filter *taskFilter) []string {
 	var lines []string
+	now := time.Now()
 
 	// Header box
 	lines = append(lines, "")
@@ -188,66 +157,90 @@ func displayTaskListWithSpinner(taskList *TaskList, fileName string, spinnerFram
 	activeCount := 0
 	pendingCount := 0
 	doneCount := 0
+	pomodoros := 0
+	sectionCounts := make(map[taskSection]int)
 
 	for i := range taskList.Items {
 		task := &taskList.Items[i]
-		switch task.Status {
-		case StatusActive:
+		if !filter.matches(task) {
+			continue
+		}
+		switch {
+		case task.Status == StatusActive || task.hasActiveChild():
 			activeCount++
-		case StatusPending, StatusPaused:
+		case task.Status == StatusPending || task.Status == StatusPaused:
 			pendingCount++
-		case StatusDone:
+		case task.Status == StatusDone:
 			doneCount++
 		}
+		sectionCounts[sectionOf(taskList, task, now)]++
+		pomodoros += task.pomodorosOn(now)
 	}
 
-	lines = append(lines, fmt.Sprintf("  Active: %d | Pending: %d | Done: %d", activeCount, pendingCount, doneCount))
-	lines = append(lines, fmt.Sprintf("  %s", strings.Repeat("-", 40)))
-	lines = append(lines, "")
-
-	if activeCount > 0 {
-		lines = append(lines, "  ACTIVE")
-		lines = append(lines, "  ------")
-		lines = append(lines, getTaskLinesWithSpinner(taskList, spinnerFrame, StatusActive)...)
-		lines = append(lines, "")
+	counts := fmt.Sprintf("  Active: %d | Pending: %d | Done: %d", activeCount, pendingCount, doneCount)
+	if sectionCounts[sectionWaiting] > 0 {
+		counts += fmt.Sprintf(" | Waiting: %d", sectionCounts[sectionWaiting])
 	}
-
-	if pendingCount > 0 {
-		lines = append(lines, "  PENDING")
-		lines = append(lines, "  -------")
-		lines = append(lines, getTaskLinesWithSpinner(taskList, spinnerFrame, StatusPending, StatusPaused)...)
-		lines = append(lines, "")
+	if sectionCounts[sectionCancelled] > 0 {
+		counts += fmt.Sprintf(" | Cancelled: %d", sectionCounts[sectionCancelled])
+	}
+	if sectionCounts[sectionOverdue] > 0 {
+		counts += fmt.Sprintf(" | Overdue: %d", sectionCounts[sectionOverdue])
+	}
+	if sectionCounts[sectionBlocked] > 0 {
+		counts += fmt.Sprintf(" | Blocked: %d", sectionCounts[sectionBlocked])
 	}
+	if pomodoros > 0 {
+		counts += fmt.Sprintf(" | Pomodoros today: %d", pomodoros)
+	}
+	lines = append(lines, counts)
+	if filter != nil {
+		lines = append(lines, fmt.Sprintf("  Filter: %s", filter))
+	}
+	if taskList.sortMode() != sortManual {
+		lines = append(lines, fmt.Sprintf("  Sorted by: %s", taskList.sortMode()))
+	}
+	lines = append(lines, fmt.Sprintf("  %s", strings.Repeat("-", 40)))
+	lines = append(lines, "")
 
-	if doneCount > 0 {
-		lines = append(lines, "  DONE")
-		lines = append(lines, "  ----")
-		lines = append(lines, getTaskLinesWithSpinner(taskList, spinnerFrame, StatusDone)...)
+	for _, section := range taskSections {
+		if sectionCounts[section] == 0 {
+			continue
+		}
+		lines = append(lines, "  "+string(section))
+		lines = append(lines, "  "+strings.Repeat("-", len(section)))
+		lines = append(lines, getTaskLinesWithSpinner(taskList, spinnerFrame, func(task *Task) bool {
+			return filter.matches(task) && sectionOf(taskList, task, now) == section
+		})...)
 		lines = append(lines, "")
 	}
 
-	footer := " <num> start/stop | add <task> | remove <num> | done <num> | r back | q quit "
-	drawFullScreen(lines, footer)
+	return lines
 }
 
 func getTaskLines(taskList *TaskList, statuses ...TaskStatus) []string {
-	return getTaskLinesWithSpinner(taskList, 0, statuses...)
+	return getTaskLinesWithSpinner(taskList, 0, func(task *Task) bool {
+		return slices.Contains(statuses, task.Status)
+	})
 }
 
-func getTaskLinesWithSpinner(taskList *TaskList, spinnerFrame int, statuses ...TaskStatus) []string {
+// getTaskLinesWithSpinner renders the tasks include returns true for.
+func getTaskLinesWithSpinner(taskList *TaskList, spinnerFrame int, include func(*Task) bool) []string {
 	spinnerChars := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	spinner := spinnerChars[spinnerFrame%len(spinnerChars)]
+	now := time.Now()
 
-	statusMap := make(map[TaskStatus]bool)
-	for _, status := range statuses {
-		statusMap[status] = true
+	var indices []int
+	for i := range taskList.Items {
+		if include(&taskList.Items[i]) {
+			indices = append(indices, i)
+		}
 	}
+	sortTaskIndices(taskList, indices, now)
 
 	var lines []string
-	for i, task := range taskList.Items {
-		if !statusMap[task.Status] {
-			continue
-		}
+	for _, i := range indices {
+		task := taskList.Items[i]
 
 		var statusIcon string
 		var timeInfo string
@@ -256,13 +249,11 @@ func getTaskLinesWithSpinner(taskList *TaskList, spinnerFrame int, statuses ...T
 		case StatusActive:
 			statusIcon = fmt.Sprintf("%s", spinner)
 			if task.ActiveStartTime != nil {
-				elapsed := time.Since(*task.ActiveStartTime).Nanoseconds()
-				totalElapsed := task.TotalDuration + elapsed
-				timeInfo = fmt.Sprintf(" [Running: %s]", formatDuration(totalElapsed))
+				timeInfo = fmt.Sprintf(" [Running: %s]", formatDuration(task.TrackedDuration(now)))
 			}
 		case StatusPending:
 			statusIcon = "[ ]"
-			if task.TotalDuration > 0 {
+			if task.TrackedDuration(now) > 0 {
 				timeInfo = fmt.Sprintf(" [Total: %s]", task.GetFormattedDuration())
 			}
 		case StatusPaused:
@@ -270,17 +261,60 @@ func getTaskLinesWithSpinner(taskList *TaskList, spinnerFrame int, statuses ...T
 			timeInfo = fmt.Sprintf(" [Paused: %s]", task.GetFormattedDuration())
 		case StatusDone:
 			statusIcon = "[x]"
-			if task.TotalDuration > 0 {
+			if task.TrackedDuration(now) > 0 {
 				timeInfo = fmt.Sprintf(" [Total: %s]", task.GetFormattedDuration())
 			}
 			if task.CompletedAt != nil {
 				timeInfo += fmt.Sprintf(" @ %s", task.CompletedAt.Format("15:04"))
 			}
+		case StatusWaiting:
+			statusIcon = "[~]"
+			if task.WaitingOn != "" {
+				timeInfo = fmt.Sprintf(" [Waiting on: %s]", task.WaitingOn)
+			}
+			if task.FollowUpDue(now) {
+				timeInfo += fmt.Sprintf(" [Follow up now: %s]", formatDue(*task.FollowUpAt, now))
+			} else if task.FollowUpAt != nil {
+				timeInfo += fmt.Sprintf(" [Follow up: %s]", formatDue(*task.FollowUpAt, now))
+			}
+		case StatusCancelled:
+			statusIcon = "[/]"
+			if task.TrackedDuration(now) > 0 {
+				timeInfo = fmt.Sprintf(" [Total: %s]", task.GetFormattedDuration())
+			}
 		}
 
-		lines = append(lines, fmt.Sprintf("  %d. %s %s%s", i+1, statusIcon, task.Title, timeInfo))
+		if task.DueAt != nil && !task.IsClosed() {
+			if task.IsOverdue(now) {
+				timeInfo += fmt.Sprintf(" [Overdue: %s]", formatDue(*task.DueAt, now))
+			} else {
+				timeInfo += fmt.Sprintf(" [Due: %s]", formatDue(*task.DueAt, now))
+			}
+		}
 
-		if len(task.Sessions) > 0 && (task.Status == StatusDone || task.Status == StatusPaused) {
+		if task.Recur != "" {
+			timeInfo += fmt.Sprintf(" [Repeats: %s]", task.Recur)
+		}
+		if pomodoros := task.pomodorosOn(now); pomodoros > 0 {
+			timeInfo += fmt.Sprintf(" [Pomodoros today: %d]", pomodoros)
+		}
+		if blocking := blockers(taskList, &task); len(blocking) > 0 && !task.IsClosed() {
+			timeInfo += fmt.Sprintf(" [Blocked by: %s]", formatBlockers(blocking))
+		}
+
+		title := task.Title
+		if task.Priority != PriorityNone {
+			title = fmt.Sprintf("(%s) %s", task.Priority, title)
+		}
+		if done, total := task.progress(); total > 0 {
+			title += fmt.Sprintf(" (%d/%d)", done, total)
+		}
+		if len(task.Tags) > 0 {
+			title += " " + formatTags(task.Tags)
+		}
+		lines = append(lines, fmt.Sprintf("  %d. %s %s %s%s", i+1, task.ShortID(), statusIcon, title, timeInfo))
+
+		if len(task.Sessions) > 0 && (task.IsClosed() || task.Status == StatusPaused) {
 			sessionInfo := fmt.Sprintf("     Sessions: %d | ", len(task.Sessions))
 			if len(task.Sessions) <= 3 {
 				for j, session := range task.Sessions {
@@ -297,6 +331,32 @@ func getTaskLinesWithSpinner(taskList *TaskList, spinnerFrame int, statuses ...T
 			}
 			lines = append(lines, sessionInfo)
 		}
+
+		lines = append(lines, getCommentLines(task.Comment)...)
+		if !task.IsClosed() {
+			lines = append(lines, getSubtaskLines(&task, i+1, spinner, now)...)
+		}
+	}
+	return lines
+}
+
+// getCommentLines shows the first few lines of a task's comment below it.
+func getCommentLines(comment string) []string {
+	const maxLines = 3
+
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return nil
+	}
+
+	var lines []string
+	commentLines := strings.Split(comment, "\n")
+	for j, line := range commentLines {
+		if j == maxLines {
+			lines = append(lines, fmt.Sprintf("     > ... +%d more lines", len(commentLines)-maxLines))
+			break
+		}
+		lines = append(lines, "     > "+strings.TrimRight(line, "\r"))
 	}
 	return lines
 }
@@ -361,19 +421,136 @@ func displayTasksByStatus(taskList *TaskList, statuses ...TaskStatus) {
 	}
 }
 
-func addTask(taskList *TaskList, title string) {
+// addTask adds a pending task. +tag words in title become tags.
+func addTask(taskList *TaskList, title string) error {
+	title, tags := parseTags(title)
+	if title == "" {
+		return fmt.Errorf("task title cannot be empty")
+	}
+
 	newTask := Task{
-		ID:            time.Now().UnixNano(),
+		ID:            newTaskID(taskList),
 		Title:         title,
 		Status:        StatusPending,
 		Comment:       "",
+		Tags:          tags,
 		Sessions:      []Session{},
 		TotalDuration: 0,
 		CreatedAt:     time.Now(),
 	}
 
 	taskList.Items = append(taskList.Items, newTask)
-	fmt.Printf("[+] Added: %s\n", title)
+	fmt.Printf("[+] Added: %s\n", strings.TrimSpace(title+" "+formatTags(tags)))
+	return nil
+}
+
+// resolveTaskRef returns the 1-based index of the task ref points at. ref is
+// either the task's position in the list, its short ID or its full ID.
+func resolveTaskRef(taskList *TaskList, ref string) (int, error) {
+	ref = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ref), "#"))
+	if ref == "" {
+		return 0, fmt.Errorf("task number or ID required")
+	}
+
+	if num, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		if num >= 1 && num <= int64(len(taskList.Items)) {
+			return int(num), nil
+		}
+		if index, err := findTaskIndex(taskList, num); err == nil {
+			return index, nil
+		}
+		return 0, fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	if !isShortID(ref) {
+		return 0, fmt.Errorf("'%s' is not a task number or ID", ref)
+	}
+
+	index := 0
+	for i := range taskList.Items {
+		if taskList.Items[i].ShortID() != ref {
+			continue
+		}
+		if index != 0 {
+			return 0, fmt.Errorf("task ID '%s' is ambiguous, use the task number or full ID", ref)
+		}
+		index = i + 1
+	}
+	if index == 0 {
+		return 0, fmt.Errorf("no task with ID '%s'", ref)
+	}
+	return index, nil
+}
+
+// resolveTaskID returns the ID of the task ref points at, so an operation can
+// still find it after the list was reloaded and reordered.
+func resolveTaskID(taskList *TaskList, ref string) (int64, error) {
+	index, err := resolveTaskRef(taskList, ref)
+	if err != nil {
+		return 0, err
+	}
+	return taskList.Items[index-1].ID, nil
+}
+
+// findTaskIndex returns the 1-based index of the task with the given ID.
+func findTaskIndex(taskList *TaskList, id int64) (int, error) {
+	for i := range taskList.Items {
+		if taskList.Items[i].ID == id {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("task no longer exists")
+}
+
+// newTaskID returns an ID whose short form isn't used in taskList yet.
+func newTaskID(taskList *TaskList) int64 {
+	used := make(map[string]bool)
+	for i := range taskList.Items {
+		used[taskList.Items[i].ShortID()] = true
+		for j := range taskList.Items[i].Children {
+			used[taskList.Items[i].Children[j].ShortID()] = true
+		}
+	}
+
+	id := time.Now().UnixNano()
+	for used[shortTaskID(id)] {
+		id++
+	}
+	return id
+}
+
+func editTaskTitle(taskList *TaskList, index int, title string) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+	title, tags := parseTags(title)
+	if title == "" {
+		return fmt.Errorf("task title cannot be empty")
+	}
+
+	task := &taskList.Items[index-1]
+	oldTitle := task.Title
+	task.Title = title
+	for _, tag := range tags {
+		task.Tags = appendTag(task.Tags, tag)
+	}
+	fmt.Printf("[~] Renamed: %s -> %s\n", oldTitle, task.Title)
+	return nil
+}
+
+func setTaskComment(taskList *TaskList, index int, comment string) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+
+	task := &taskList.Items[index-1]
+	task.Comment = strings.TrimSpace(comment)
+	if task.Comment == "" {
+		fmt.Printf("[~] Note cleared: %s\n", task.Title)
+	} else {
+		fmt.Printf("[~] Note saved: %s\n", task.Title)
+	}
+	return nil
 }
 
 func removeTask(taskList *TaskList, index int) error {
@@ -383,11 +560,46 @@ func removeTask(taskList *TaskList, index int) error {
 
 	removedTask := taskList.Items[index-1]
 	taskList.Items = append(taskList.Items[:index-1], taskList.Items[index:]...)
+	dropDependency(taskList, removedTask.ID)
 
 	fmt.Printf("[-] Removed: %s\n", removedTask.Title)
 	return nil
 }
 
+// moveTask moves the task at index to position pos in the list.
+func moveTask(taskList *TaskList, index int, pos int) error {
+	if index < 1 || index > len(taskList.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
+	}
+	if pos < 1 || pos > len(taskList.Items) {
+		return fmt.Errorf("invalid position. Use 1-%d", len(taskList.Items))
+	}
+
+	task := taskList.Items[index-1]
+	taskList.Items = slices.Delete(taskList.Items, index-1, index)
+	taskList.Items = slices.Insert(taskList.Items, pos-1, task)
+	fmt.Printf("[~] Moved: %s to position %d\n", task.Title, pos)
+	return nil
+}
+
+// moveTaskToList moves the task at index from one list to the end of
+// another, keeping its sessions and everything else it carries.
+func moveTaskToList(from *TaskList, index int, to *TaskList, toName string) error {
+	if index < 1 || index > len(from.Items) {
+		return fmt.Errorf("invalid task number. Use 1-%d", len(from.Items))
+	}
+
+	task := from.Items[index-1]
+	if _, err := findTaskIndex(to, task.ID); err == nil {
+		return fmt.Errorf("'%s' is already in %s", task.Title, toName)
+	}
+
+	to.Items = append(to.Items, task)
+	from.Items = slices.Delete(from.Items, index-1, index)
+	fmt.Printf("[>] Moved: %s -> %s\n", task.Title, toName)
+	return nil
+}
+
 func toggleTaskTimer(taskList *TaskList, index int) error {
 	if index < 1 || index > len(taskList.Items) {
 		return fmt.Errorf("invalid task number. Use 1-%d", len(taskList.Items))
@@ -398,17 +610,23 @@ func toggleTaskTimer(taskList *TaskList, index int) error {
 	if task.Status == StatusDone {
 		return fmt.Errorf("cannot start timer for completed task")
 	}
+	if task.Status == StatusCancelled {
+		return fmt.Errorf("cannot start timer for cancelled task")
+	}
 
 	now := time.Now()
 
 	switch task.Status {
-	case StatusPending, StatusPaused:
-		for i := range taskList.Items {
-			if taskList.Items[i].Status == StatusActive {
-				stopTaskTimer(&taskList.Items[i], now)
-			}
+	case StatusPending, StatusPaused, StatusWaiting:
+		if blocking := blockers(taskList, task); len(blocking) > 0 {
+			return fmt.Errorf("'%s' is blocked by %s, finish that first", task.Title, formatBlockers(blocking))
+		}
+		if task.IsWaiting() {
+			task.clearWaiting()
+			fmt.Printf("[i] No longer waiting: %s\n", task.Title)
 		}
-		task.Status = StatusActive
+		stopAllTimers(taskList, now)
+		task.setStatus(StatusActive, now)
 		task.ActiveStartTime = &now
 		fmt.Printf("[>] Started: %s\n", task.Title)
 
@@ -428,16 +646,9 @@ func stopTaskTimer(task *Task, endTime time.Time) {
 		return
 	}
 
-	duration := endTime.Sub(*task.ActiveStartTime)
-	session := Session{
-		StartTime: *task.ActiveStartTime,
-		EndTime:   endTime,
-		Duration:  duration.Nanoseconds(),
-	}
-
-	task.Sessions = append(task.Sessions, session)
-	task.TotalDuration += duration.Nanoseconds()
-	task.Status = StatusPaused
+	task.Sessions = append(task.Sessions, newSession(*task.ActiveStartTime, endTime))
+	task.recomputeDuration()
+	task.setStatus(StatusPaused, endTime)
 	task.ActiveStartTime = nil
 }
 
@@ -449,25 +660,39 @@ func markTaskComplete(taskList *TaskList, index int) error {
 	task := &taskList.Items[index-1]
 	now := time.Now()
 
+	if task.IsDone() {
+		return reopenTask(taskList, index)
+	}
+
 	if task.Status == StatusActive {
 		stopTaskTimer(task, now)
 	}
+	for i := range task.Children {
+		if task.Children[i].IsActive() {
+			stopTaskTimer(&task.Children[i], now)
+		}
+	}
 
-	task.Status = StatusDone
+	task.clearWaiting()
+	task.setStatus(StatusDone, now)
 	task.CompletedAt = &now
 
 	totalTime := ""
-	if task.TotalDuration > 0 {
+	if task.TrackedDuration(now) > 0 {
 		totalTime = fmt.Sprintf(" [Total time: %s]", task.GetFormattedDuration())
 	}
 
 	fmt.Printf("[x] Completed: %s%s\n", task.Title, totalTime)
+
+	if task.Recur != "" {
+		spawnNextInstance(taskList, task, now)
+	}
 	return nil
 }
 
 func hasActiveTask(taskList *TaskList) bool {
 	for i := range taskList.Items {
-		if taskList.Items[i].Status == StatusActive {
+		if taskList.Items[i].Status == StatusActive || taskList.Items[i].hasActiveChild() {
 			return true
 		}
 	}
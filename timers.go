@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// With the single-timer setting only one timer runs in the whole task
+// directory: starting a task pauses the timers running in other lists.
+
+// updateTimer is updateTasks for an operation that starts or stops a timer
+// in listName. With single-timer on, every list is locked while the timers
+// are looked for, and lists with a running timer are saved together with
+// listName, see updateLists.
+func updateTimer(config *Config, store Store, listName string, taskList *TaskList, op func(*TaskList) error) error {
+	if !config.SingleTimer {
+		return updateTasks(store, listName, taskList, op)
+	}
+
+	names := []string{listName}
+	taskLists := []*TaskList{taskList}
+	listNames, err := store.Lists()
+	if err != nil {
+		return err
+	}
+	// No other list can start a timer between loading and saving.
+	unlock, err := lockLists(store, listNames)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, name := range listNames {
+		if name == listName {
+			continue
+		}
+		other, err := store.Load(name)
+		if err != nil {
+			return fmt.Errorf("load error: %s: %v", name, err)
+		}
+		if hasActiveTask(other) {
+			names = append(names, name)
+			taskLists = append(taskLists, other)
+		}
+	}
+
+	return updateLockedLists(store, names, taskLists, func() error {
+		// Pausing a stale timer would record a session of many hours.
+		now := time.Now()
+		for i := 1; i < len(taskLists); i++ {
+			for _, timer := range staleTimers(taskLists[i], now, config.staleAfter()) {
+				return fmt.Errorf("'%s' in %s has been running since %s, run 'tgo recover %s --list %s' first",
+					timer.Title, names[i], timer.ActiveStartTime.Local().Format("Mon 2006-01-02 15:04"), anyTaskRef(taskLists[i], timer), names[i])
+			}
+		}
+
+		if err := op(taskList); err != nil {
+			return err
+		}
+		if !hasActiveTask(taskList) {
+			return nil
+		}
+		for i := 1; i < len(taskLists); i++ {
+			for _, timer := range listTimers(taskLists[i]) {
+				stopTaskTimer(timer, now)
+				fmt.Printf("[|] Paused in %s: %s\n", names[i], timer.Title)
+			}
+		}
+		return nil
+	})
+}
+
+// listTimers returns the tasks and subtasks of a list whose timer runs.
+func listTimers(taskList *TaskList) []*Task {
+	var running []*Task
+	for i := range taskList.Items {
+		task := &taskList.Items[i]
+		if task.IsActive() {
+			running = append(running, task)
+		}
+		for j := range task.Children {
+			if task.Children[j].IsActive() {
+				running = append(running, &task.Children[j])
+			}
+		}
+	}
+	return running
+}
+
+// runningTimer is a timer running somewhere in the task directory.
+type runningTimer struct {
+	listName string
+	taskList *TaskList
+	task     *Task
+}
+
+func findRunningTimers(store Store) ([]runningTimer, error) {
+	listNames, err := store.Lists()
+	if err != nil {
+		return nil, err
+	}
+
+	var timers []runningTimer
+	for _, name := range listNames {
+		taskList, err := store.Load(name)
+		if err != nil {
+			return nil, fmt.Errorf("load error: %s: %v", name, err)
+		}
+		for _, task := range listTimers(taskList) {
+			timers = append(timers, runningTimer{listName: name, taskList: taskList, task: task})
+		}
+	}
+	return timers, nil
+}
+
+// showStatus prints which timers are running, in any list.
+func showStatus(config *Config, format string) error {
+	store, err := openStore(config)
+	if err != nil {
+		return err
+	}
+	timers, err := findRunningTimers(store)
+	if err != nil {
+		return err
+	}
+	return writeStatus(os.Stdout, timers, format)
+}
+
+func getStatusLines(timers []runningTimer, now time.Time) []string {
+	if len(timers) == 0 {
+		return []string{"[i] No timer running"}
+	}
+	var lines []string
+	for _, timer := range timers {
+		lines = append(lines, fmt.Sprintf("[>] %s: %s (%s) [Running: %s] [Total: %s] since %s",
+			timer.listName,
+			timer.task.Title,
+			anyTaskRef(timer.taskList, timer.task),
+			formatDuration(now.Sub(*timer.task.ActiveStartTime).Nanoseconds()),
+			formatDuration(timer.task.TrackedDuration(now)),
+			timer.task.ActiveStartTime.Local().Format("15:04")))
+	}
+	return lines
+}
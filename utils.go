@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
 	"time"
 
@@ -49,6 +52,50 @@ func drawFullScreen(content []string, footer string) {
 	fmt.Print(footer)
 }
 
+var (
+	stdinReader = bufio.NewReader(os.Stdin)
+	// stdinLine is the read of stdin in progress, nil if there is none.
+	stdinLine chan string
+)
+
+// nextLine delivers the next line typed on stdin, the channel is closed when
+// stdin is. Reading runs in a goroutine, so waiting for input can be combined
+// with a timer (see the pomodoro). stdin is only read while someone waits for
+// a line, an editor started in between gets the keystrokes. A read left
+// running by a caller that stopped waiting is handed to the next caller. The
+// caller passes what it received to takeLine.
+func nextLine() <-chan string {
+	if stdinLine == nil {
+		line := make(chan string, 1)
+		go func() {
+			text, _ := stdinReader.ReadString('\n')
+			if text != "" {
+				line <- text
+			} else {
+				close(line)
+			}
+		}()
+		stdinLine = line
+	}
+	return stdinLine
+}
+
+// takeLine marks the line received from nextLine as read and strips its
+// newline. Once stdin is closed, nextLine keeps returning the closed channel.
+func takeLine(line string, ok bool) (string, bool) {
+	if ok {
+		stdinLine = nil
+	}
+	return strings.TrimRight(line, "\r\n"), ok
+}
+
+// readLine waits for the next line typed on stdin, without its newline. It
+// returns false when stdin is closed.
+func readLine() (string, bool) {
+	line, ok := <-nextLine()
+	return takeLine(line, ok)
+}
+
 func formatDuration(nanoseconds int64) string {
 	if nanoseconds == 0 {
 		return "0s"
@@ -66,3 +113,54 @@ func formatDuration(nanoseconds int64) string {
 	}
 	return fmt.Sprintf("%ds", seconds)
 }
+
+// editText lets the user edit text in $VISUAL or $EDITOR and returns the
+// result.
+func editText(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "tgo-note-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.WriteString(text)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	args := append(strings.Fields(editor), tmp.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor failed: %v", err)
+	}
+
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// splitFirstWord splits "3 some text" into "3" and "some text".
+func splitFirstWord(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	first, rest, _ := strings.Cut(s, " ")
+	return first, strings.TrimSpace(rest)
+}